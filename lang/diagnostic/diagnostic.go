@@ -0,0 +1,102 @@
+// Package diagnostic holds the errors reported by the lexers, the parsers
+// and the evaluator, and renders them under the source line they point at.
+package diagnostic
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// Code identifies the kind of a diagnostic
+type Code string
+
+const (
+	Lexical Code = "lexical" // malformed tokens
+	Syntax  Code = "syntax"  // malformed programs
+	Runtime Code = "runtime" // errors raised while evaluating
+	Limit   Code = "limit"   // too many errors to go on
+)
+
+// Position is a line and a column, both counted from 1,
+// columns are counted in runes
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Span is the source range a diagnostic points at, End is exclusive
+type Span struct {
+	File  string
+	Start Position
+	End   Position
+}
+
+// Point returns the span of the single char at line and column
+func Point(line, column int) Span {
+	return Span{Start: Position{line, column}, End: Position{line, column + 1}}
+}
+
+// IsZero checks if the span points nowhere
+func (s Span) IsZero() bool {
+	return s.Start.Line == 0
+}
+
+func (s Span) String() string {
+	if s.File == "" {
+		return fmt.Sprintf("%d:%d", s.Start.Line, s.Start.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", s.File, s.Start.Line, s.Start.Column)
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Message  string
+	Span     Span
+	Notes    []string // facts that explain the diagnostic
+	Hints    []string // suggestions to fix it
+}
+
+// Errorf returns an error diagnostic at span
+func Errorf(span Span, code Code, format string, a ...interface{}) Diagnostic {
+	return Diagnostic{Severity: Error, Code: code, Message: fmt.Sprintf(format, a...), Span: span}
+}
+
+// WithNote returns a copy of the diagnostic with a note added
+func (d Diagnostic) WithNote(format string, a ...interface{}) Diagnostic {
+	d.Notes = append(slices.Clip(d.Notes), fmt.Sprintf(format, a...))
+	return d
+}
+
+// WithHint returns a copy of the diagnostic with a hint added
+func (d Diagnostic) WithHint(format string, a ...interface{}) Diagnostic {
+	d.Hints = append(slices.Clip(d.Hints), fmt.Sprintf(format, a...))
+	return d
+}
+
+// Error returns the message followed by the position, if any
+func (d Diagnostic) Error() string {
+	if d.Span.IsZero() {
+		return d.Message
+	}
+	return fmt.Sprintf("%s at %d:%d", d.Message, d.Span.Start.Line, d.Span.Start.Column)
+}
@@ -0,0 +1,68 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticError(t *testing.T) {
+	d := Errorf(Point(3, 7), Syntax, "unexpected %s", "}")
+	assert.Equal(t, "unexpected } at 3:7", d.Error())
+
+	d = Errorf(Span{}, Runtime, "division by zero")
+	assert.Equal(t, "division by zero", d.Error())
+}
+
+func TestWithNoteCopies(t *testing.T) {
+	d := Errorf(Point(1, 1), Lexical, "unterminated block comment")
+	a := d.WithNote("first")
+	b := d.WithNote("second")
+
+	assert.Equal(t, 0, len(d.Notes))
+	assert.Equal(t, []string{"first"}, a.Notes)
+	assert.Equal(t, []string{"second"}, b.Notes)
+}
+
+func TestRender(t *testing.T) {
+	src := "let x = 1;\nlet = 5;\n"
+	d := Errorf(Span{File: "main.monkey", Start: Position{2, 5}, End: Position{2, 6}}, Syntax,
+		"expected next token to be IDENT, got = instead").WithHint("name the binding")
+
+	var out strings.Builder
+	Render(&out, src, d)
+
+	expected := `error[syntax]: expected next token to be IDENT, got = instead
+ --> main.monkey:2:5
+  |
+2 | let = 5;
+  |     ^
+  = hint: name the binding
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRenderWideSpanAndTabs(t *testing.T) {
+	src := "\tputs(\"open"
+	d := Errorf(Span{Start: Position{1, 7}, End: Position{1, 12}}, Lexical, "unterminated string")
+
+	var out strings.Builder
+	Render(&out, src, d)
+
+	expected := "error[lexical]: unterminated string\n" +
+		" --> 1:7\n" +
+		"  |\n" +
+		"1 | \tputs(\"open\n" +
+		"  | \t     ^^^^^\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRenderWithoutSpan(t *testing.T) {
+	d := Errorf(Span{}, Runtime, "division by zero").WithNote("in function f")
+
+	var out strings.Builder
+	Render(&out, "", d)
+
+	assert.Equal(t, "error[runtime]: division by zero\n = note: in function f\n", out.String())
+}
@@ -0,0 +1,88 @@
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Render writes the diagnostics in the form
+//
+//	error[syntax]: expected next token to be IDENT, got = instead
+//	 --> main.monkey:1:5
+//	  |
+//	1 | let = 5;
+//	  |     ^
+//	  = hint: name the binding
+//
+// src is the source the spans point into, the source line is left out
+// when the span is outside of it
+func Render(w io.Writer, src string, diags ...Diagnostic) {
+	var lines []string
+	if src != "" {
+		lines = strings.Split(src, "\n")
+	}
+	for _, d := range diags {
+		fmt.Fprintf(w, "%s[%s]: %s\n", d.Severity, d.Code, d.Message)
+		if d.Span.IsZero() {
+			renderFooter(w, "", d)
+			continue
+		}
+
+		line := d.Span.Start.Line
+		gutter := strings.Repeat(" ", len(strconv.Itoa(line)))
+		fmt.Fprintf(w, "%s--> %s\n", gutter, d.Span)
+		if line > len(lines) {
+			renderFooter(w, gutter, d)
+			continue
+		}
+
+		text := strings.TrimSuffix(lines[line-1], "\r")
+		fmt.Fprintf(w, "%s |\n", gutter)
+		fmt.Fprintf(w, "%d | %s\n", line, text)
+		fmt.Fprintf(w, "%s | %s\n", gutter, underline(text, d.Span))
+		renderFooter(w, gutter, d)
+	}
+}
+
+// underline returns the carets under the span in text, the text before them
+// is blanked keeping its tabs so they line up
+func underline(text string, span Span) string {
+	var out strings.Builder
+	column := 1
+	for _, ch := range text {
+		if column >= span.Start.Column {
+			break
+		}
+		if ch == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteByte(' ')
+		}
+		column++
+	}
+	for ; column < span.Start.Column; column++ {
+		out.WriteByte(' ')
+	}
+
+	width := 1
+	if span.End.Line == span.Start.Line && span.End.Column > span.Start.Column {
+		width = span.End.Column - span.Start.Column
+	} else if span.End.Line > span.Start.Line {
+		// the span goes on past the line, underline up to its end
+		width = max(utf8.RuneCountInString(text)-span.Start.Column+1, 1)
+	}
+	out.WriteString(strings.Repeat("^", width))
+	return out.String()
+}
+
+func renderFooter(w io.Writer, gutter string, d Diagnostic) {
+	for _, note := range d.Notes {
+		fmt.Fprintf(w, "%s = note: %s\n", gutter, note)
+	}
+	for _, hint := range d.Hints {
+		fmt.Fprintf(w, "%s = hint: %s\n", gutter, hint)
+	}
+}
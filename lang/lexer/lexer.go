@@ -7,6 +7,7 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/queue"
 	"github.com/delavalom/arvlang/lang/syntax"
 	"github.com/delavalom/arvlang/lang/tokens"
@@ -40,7 +41,7 @@ type Lexer struct {
 	input      []byte
 	tokenQueue TokenQueue
 	charQueue  CharQueue
-	errors     []string
+	errors     []diagnostic.Diagnostic
 	line       int
 	column     int
 	cursor     int
@@ -56,7 +57,7 @@ func NewLexer(input []byte) *Lexer {
 		input:      input,
 		tokenQueue: tokenQueue,
 		charQueue:  charQueue,
-		errors:     []string{},
+		errors:     []diagnostic.Diagnostic{},
 		line:       line,
 		column:     column,
 		cursor:     0,
@@ -94,21 +95,31 @@ func (l *Lexer) HasError() bool {
 // GetError returns an error if the lexer has errors
 func (l *Lexer) GetError() error {
 	if l.HasError() {
-		return fmt.Errorf("tokenizer errors: \n- %s", strings.Join(l.errors, "\n- "))
+		messages := make([]string, len(l.errors))
+		for i, err := range l.errors {
+			messages[i] = err.Error()
+		}
+		return fmt.Errorf("tokenizer errors: \n- %s", strings.Join(messages, "\n- "))
 	}
 	return nil
 }
 
-// RegisterError registers an error in the lexer by appending the error message
+// Diagnostics returns the errors registered in the lexer
+func (l *Lexer) Diagnostics() []diagnostic.Diagnostic {
+	return l.errors
+}
+
+// RegisterError registers an error in the lexer at the position of the given char
 func (l *Lexer) RegisterError(e string, c *char) {
 	if l.TooManyErrors() {
 		return
 	}
 
-	l.errors = append(l.errors, fmt.Sprintf("%s at %d:%d", e, c.Line, c.Column))
+	span := diagnostic.Point(c.Line, c.Column)
+	l.errors = append(l.errors, diagnostic.Errorf(span, diagnostic.Lexical, "%s", e))
 
 	if l.TooManyErrors() {
-		l.errors = append(l.errors, "too many errors, aborting")
+		l.errors = append(l.errors, diagnostic.Errorf(diagnostic.Span{}, diagnostic.Limit, "too many errors, aborting"))
 	}
 }
 
@@ -163,7 +174,8 @@ func (l *Lexer) isDoubleOperator(a rune, b rune) bool {
 		a == '<' && b == '=',
 		a == '>' && b == '=',
 		a == '=' && b == '=',
-		a == '!' && b == '=':
+		a == '!' && b == '=',
+		a == '|' && b == '>':
 		return true
 	default:
 		return false
@@ -179,7 +191,8 @@ func (l *Lexer) isOperator(r rune) bool {
 		r == '/',
 		r == '%',
 		r == '<',
-		r == '>':
+		r == '>',
+		r == '?':
 		return true
 	default:
 		return false
@@ -460,6 +473,9 @@ func (l *Lexer) parseNextToken() *tokens.Token {
 		case currentChar.Is(','):
 			token = tokens.New(tokens.Comma, ",", currentChar.Line, currentChar.Column)
 			l.charQueue.Dequeue()
+		case currentChar.Is(':'):
+			token = tokens.New(tokens.Colon, ":", currentChar.Line, currentChar.Column)
+			l.charQueue.Dequeue()
 		case currentChar.Is('{'):
 			token = tokens.New(tokens.LeftBrace, "{", currentChar.Line, currentChar.Column)
 			l.charQueue.Dequeue()
@@ -3,6 +3,7 @@ package lexer
 import (
 	"testing"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/tokens"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,11 +16,12 @@ func _createToken(t tokens.Type, l string) *tokens.Token {
 }
 
 func TestTokenizeSymbols(t *testing.T) {
-	input := `; , . { } ( ) [ ]`
+	input := `; , : . { } ( ) [ ]`
 
 	expected := []*tokens.Token{
 		_createToken(tokens.Semicolon, ";"),
 		_createToken(tokens.Comma, ","),
+		_createToken(tokens.Colon, ":"),
 		_createToken(tokens.Dot, "."),
 		_createToken(tokens.LeftBrace, "{"),
 		_createToken(tokens.RightBrace, "}"),
@@ -40,7 +42,7 @@ func TestTokenizeSymbols(t *testing.T) {
 }
 
 func TestTokenizeOperators(t *testing.T) {
-	input := `+ - * / % ** < <= > >= == !=`
+	input := `+ - * / % ** < <= > >= == != |> ?`
 
 	expected := []*tokens.Token{
 		_createToken(tokens.Operator, "+"),
@@ -55,6 +57,8 @@ func TestTokenizeOperators(t *testing.T) {
 		_createToken(tokens.Operator, ">="),
 		_createToken(tokens.Operator, "=="),
 		_createToken(tokens.Operator, "!="),
+		_createToken(tokens.Operator, "|>"),
+		_createToken(tokens.Operator, "?"),
 	}
 
 	result, err := Tokenize([]byte(input))
@@ -216,3 +220,16 @@ func TestInvalidCharacter(t *testing.T) {
 	_, err := Tokenize([]byte(input))
 	assert.NotEqual(t, err, nil)
 }
+
+func TestErrorDiagnostics(t *testing.T) {
+	input := "let a = 1\nlet b = ☂"
+	l := NewLexer([]byte(input))
+	for !l.parseNextToken().Is(tokens.EOF) {
+	}
+
+	diagnostics := l.Diagnostics()
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, diagnostic.Lexical, diagnostics[0].Code)
+	assert.Equal(t, diagnostic.Position{Line: 2, Column: 9}, diagnostics[0].Span.Start)
+	assert.Equal(t, "invalid character '☂' at 2:9", diagnostics[0].Error())
+}
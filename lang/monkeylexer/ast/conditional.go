@@ -0,0 +1,19 @@
+package ast
+
+import (
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
+
+// ConditionalExpression is the expression form of an if, c ? a : b
+type ConditionalExpression struct {
+	Token       token.Token // The ? token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Condition.String() + " ? " + ce.Consequence.String() + " : " + ce.Alternative.String() + ")"
+}
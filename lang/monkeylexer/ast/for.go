@@ -0,0 +1,18 @@
+package ast
+
+import "github.com/delavalom/arvlang/lang/monkeylexer/token"
+
+// ForExpression runs its body once for each element of an iterable,
+// bound to the loop variable
+type ForExpression struct {
+	Token    token.Token // The 'for' token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode()      {}
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForExpression) String() string {
+	return "for " + fe.Variable.String() + " range " + fe.Iterable.String() + " " + fe.Body.String()
+}
@@ -1,24 +1,47 @@
 package ast
 
-import "github.com/delavalom/arvlang/lang/monkeylexer/token"
+import (
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
 
 type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
 	Parameters []*Identifier
-	Body       *BlockStatement
+	// Defaults holds the default value of each parameter, nil for the
+	// parameters without one
+	Defaults []Expression
+	Rest     *Identifier // the ...rest parameter, nil when there is none
+	Body     *BlockStatement
+	Name     string // the name of the let binding the function, if any
+	// Generator is set when the body yields, outside of nested functions
+	Generator bool
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	out := fl.TokenLiteral() + "("
-	for i, param := range fl.Parameters {
-		if i != 0 {
-			out += ", "
-		}
-		out += param.String()
-	}
+	out += ParametersString(fl.Parameters, fl.Defaults, fl.Rest)
 	out += ") "
 	out += fl.Body.String()
 	return out
 }
+
+// ParametersString returns the parameter list of a function,
+// without the parentheses
+func ParametersString(params []*Identifier, defaults []Expression, rest *Identifier) string {
+	out := []string{}
+	for i, param := range params {
+		if i < len(defaults) && defaults[i] != nil {
+			out = append(out, param.String()+" = "+defaults[i].String())
+		} else {
+			out = append(out, param.String())
+		}
+	}
+	if rest != nil {
+		out = append(out, "..."+rest.String())
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,14 @@
+package ast
+
+import "github.com/delavalom/arvlang/lang/monkeylexer/token"
+
+type ImportExpression struct {
+	Token token.Token // the 'import' token
+	Path  string
+}
+
+func (ie *ImportExpression) expressionNode()      {}
+func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " \"" + ie.Path + "\""
+}
@@ -1,12 +1,16 @@
 package ast
 
 import (
+	"math/big"
+
 	"github.com/delavalom/arvlang/lang/monkeylexer/token"
 )
 
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	// Big holds the value instead of Value when it is out of the int64 range
+	Big *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
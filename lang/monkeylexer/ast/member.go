@@ -0,0 +1,18 @@
+package ast
+
+import (
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
+
+// MemberExpression is the access to a field by name, obj.field
+type MemberExpression struct {
+	Token    token.Token // The . token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
@@ -0,0 +1,34 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
+
+// templateEscaper escapes the text of a template so it reads back the same
+var templateEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "${", `\${`)
+
+type TemplateLiteral struct {
+	Token token.Token  // the token.TEMPLATE token
+	Parts []Expression // *StringLiteral for the text, any expression for ${}
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+	out.WriteString("`")
+	for _, part := range tl.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(templateEscaper.Replace(text.Value))
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteString("`")
+	return out.String()
+}
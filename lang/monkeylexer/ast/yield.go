@@ -0,0 +1,15 @@
+package ast
+
+import "github.com/delavalom/arvlang/lang/monkeylexer/token"
+
+// YieldStatement sends a value out of a generator function
+type YieldStatement struct {
+	Token token.Token // the 'yield' token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	return ys.Token.Literal + " " + ys.Value.String() + ";"
+}
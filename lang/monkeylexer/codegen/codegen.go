@@ -0,0 +1,223 @@
+// Package codegen transpiles a Monkey program into Go source that runs on
+// top of the codegen/runtime package.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+)
+
+const runtimeImport = "github.com/delavalom/arvlang/lang/monkeylexer/codegen/runtime"
+
+// scope holds the names bound by a Monkey function (or the program),
+// lets are hoisted so closures can refer to bindings declared after them
+type scope struct {
+	outer    *scope
+	lets     []string
+	declared map[string]bool
+	hoisted  map[string]bool
+	used     map[string]bool
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, declared: map[string]bool{}, hoisted: map[string]bool{}, used: map[string]bool{}}
+}
+
+// declare binds a parameter
+func (s *scope) declare(name string) {
+	s.declared[name] = true
+}
+
+// hoist binds a let, unless a parameter or another let already binds name
+func (s *scope) hoist(name string) {
+	if !s.declared[name] {
+		s.declared[name] = true
+		s.hoisted[name] = true
+		s.lets = append(s.lets, name)
+	}
+}
+
+// resolve marks name as used in the scope that binds it, hoisted reports
+// whether it is bound by a let, which may not have run yet
+func (s *scope) resolve(name string) (ok, hoisted bool) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if sc.declared[name] {
+			sc.used[name] = true
+			return true, sc.hoisted[name]
+		}
+	}
+	return false, false
+}
+
+type generator struct {
+	scope *scope
+	// ifDepth counts the if expressions compiled as Go function literals,
+	// where a return statement would not leave the Monkey function
+	ifDepth int
+}
+
+// Generate emits a gofmt'd Go file of package pkg from program, the program
+// statements become the body of a Run function and, for package main, a
+// main function runs it
+func Generate(program *ast.Program, pkg string) ([]byte, error) {
+	g := &generator{}
+
+	body, err := g.function(&ast.FunctionLiteral{}, program.Statements)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by monkeylexer codegen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	fmt.Fprintf(&out, "import %q\n\n", runtimeImport)
+	fmt.Fprintf(&out, "func Run() runtime.Object {\n%s}\n", body)
+	if pkg == "main" {
+		fmt.Fprintf(&out, "\nfunc main() {\n\truntime.Main(Run)\n}\n")
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("codegen: invalid Go source: %w", err)
+	}
+	return src, nil
+}
+
+// function compiles the body of a Monkey function, binding its parameters
+// from args and declaring its hoisted lets before the statements
+func (g *generator) function(fn *ast.FunctionLiteral, statements []ast.Statement) (string, error) {
+	g.scope = newScope(g.scope)
+	defer func() { g.scope = g.scope.outer }()
+
+	for _, param := range fn.Parameters {
+		g.scope.declare(param.Value)
+	}
+	if fn.Rest != nil {
+		g.scope.declare(fn.Rest.Value)
+	}
+	for _, def := range fn.Defaults {
+		hoistLets(def, g.scope)
+	}
+	for _, stmt := range statements {
+		hoistLets(stmt, g.scope)
+	}
+
+	// defaults are compiled first so the parameters they use are marked used
+	defaults := make([]string, len(fn.Defaults))
+	for i, def := range fn.Defaults {
+		if def == nil {
+			continue
+		}
+		code, err := g.expression(def)
+		if err != nil {
+			return "", err
+		}
+		defaults[i] = code
+	}
+
+	var body strings.Builder
+	if err := g.block(&body, statements, true); err != nil {
+		return "", err
+	}
+
+	// the lets are declared first, a default value can refer to them
+	var header strings.Builder
+	for _, name := range g.scope.lets {
+		fmt.Fprintf(&header, "var %s runtime.Object\n", goName(name))
+		if !g.scope.used[name] {
+			fmt.Fprintf(&header, "_ = %s\n", goName(name))
+		}
+	}
+	for i, param := range fn.Parameters {
+		name := goName(param.Value)
+		if defaults[i] != "" {
+			fmt.Fprintf(&header, "var %s runtime.Object\nif len(args) > %d {\n%s = args[%d]\n} else {\n%s = %s\n}\n",
+				name, i, name, i, name, defaults[i])
+			if !g.scope.used[param.Value] {
+				fmt.Fprintf(&header, "_ = %s\n", name)
+			}
+		} else if g.scope.used[param.Value] {
+			fmt.Fprintf(&header, "%s := args[%d]\n", name, i)
+		}
+	}
+	if fn.Rest != nil && g.scope.used[fn.Rest.Value] {
+		fmt.Fprintf(&header, "%s := runtime.Rest(args, %d)\n", goName(fn.Rest.Value), len(fn.Parameters))
+	}
+	return header.String() + body.String(), nil
+}
+
+// hoistLets declares in sc the names bound by the let statements of node,
+// blocks share the environment of their function so they are searched too
+func hoistLets(node ast.Node, sc *scope) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		sc.hoist(node.Name.Value)
+		hoistLets(node.Value, sc)
+	case *ast.ExpressionStatement:
+		hoistLets(node.Expression, sc)
+	case *ast.ReturnStatement:
+		hoistLets(node.ReturnValue, sc)
+	case *ast.YieldStatement:
+		hoistLets(node.Value, sc)
+	case *ast.ForExpression:
+		sc.hoist(node.Variable.Value)
+		hoistLets(node.Iterable, sc)
+		hoistLets(node.Body, sc)
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			hoistLets(stmt, sc)
+		}
+	case *ast.IfExpression:
+		hoistLets(node.Condition, sc)
+		hoistLets(node.Consequence, sc)
+		if node.Alternative != nil {
+			hoistLets(node.Alternative, sc)
+		}
+	case *ast.ConditionalExpression:
+		hoistLets(node.Condition, sc)
+		hoistLets(node.Consequence, sc)
+		hoistLets(node.Alternative, sc)
+	case *ast.PrefixExpression:
+		hoistLets(node.Right, sc)
+	case *ast.InfixExpression:
+		hoistLets(node.Left, sc)
+		hoistLets(node.Right, sc)
+	case *ast.CallExpression:
+		hoistLets(node.Function, sc)
+		for _, arg := range node.Arguments {
+			hoistLets(arg, sc)
+		}
+	case *ast.IndexExpression:
+		hoistLets(node.Left, sc)
+		hoistLets(node.Index, sc)
+	case *ast.MemberExpression:
+		hoistLets(node.Object, sc)
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			hoistLets(el, sc)
+		}
+	case *ast.TemplateLiteral:
+		for _, part := range node.Parts {
+			hoistLets(part, sc)
+		}
+	case *ast.HashLiteral:
+		for _, key := range node.Keys {
+			hoistLets(key, sc)
+			hoistLets(node.Pairs[key], sc)
+		}
+	}
+}
+
+// goName returns the Go identifier of a Monkey name, names that are
+// reserved in the generated code get a trailing underscore
+func goName(name string) string {
+	if token.IsKeyword(name) || name == "runtime" || name == "args" || strings.HasSuffix(name, "_") {
+		return name + "_"
+	}
+	return name
+}
@@ -0,0 +1,131 @@
+package codegen
+
+import (
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestGenerateIsFormatted(t *testing.T) {
+	input := `let add = fn(x, y) { x + y }; let type = add(1, 2); {"a": [type, true]}["a"]`
+	src, err := Generate(parse(t, input), "scaffold")
+	if err != nil {
+		t.Fatalf("Generate returned error: %s", err)
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %s", err)
+	}
+	if string(formatted) != string(src) {
+		t.Errorf("generated source is not gofmt'd:\n%s", src)
+	}
+	for _, want := range []string{"package scaffold", "func Run() runtime.Object", "var type_ runtime.Object"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "func main()") {
+		t.Errorf("non main package has a main function:\n%s", src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"foobar", "codegen: identifier not found: foobar"},
+		{`import "lib"`, "codegen: *ast.ImportExpression is not supported"},
+		{"let f = fn() { let x = if (true) { return 1; }; x };", "codegen: return inside an if expression is not supported"},
+	}
+	for _, tt := range tests {
+		_, err := Generate(parse(t, tt.input), "main")
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expected, err)
+		}
+	}
+}
+
+func TestGeneratedProgramRuns(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	input := `
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let max = fn(a, b) { if (a > b) { return a; } b };
+let person = {"name": "monkey", "langs": ["go"]};
+puts(fib(15), max(3, 7), len(person["name"]), push(person["langs"], "arv"));
+puts(if (1 > 2) { 1 }, "done");
+let config = {"name": "arv", "tags": ["go"]};
+puts(config.name, config.tags[0]);
+puts(config.name.upper(), [1, 2, 3].map(fn(x) { x * 2 }).join("-"));
+puts(config.tags |> push("arv") |> last);
+let evens = fn(limit) { let n = 0; for x range [1, 2, 3, 4, 5, 6] { if (x > limit) { return n; } if (x - x / 2 * 2 == 0) { yield x; } } };
+let firstEven = fn() { for x range evens(100) { return x; } };
+for x range evens(4) { puts(x); }
+puts(next(evens(6)), firstEven());
+puts(if ([]) { "full" } else { "empty" }, false && puts("never"), 0 || "x");
+puts(max(1, 2) > 1 ? "big" : puts("never"), [] ? 1 : 0);
+puts(18446744073709551616 / 2);
+let greet = fn(name, greeting = "hi", ...rest) { puts(greeting, name, rest) };
+greet("monkey");
+greet("monkey", "hello", 1, 2);
+`
+	out, err := runGenerated(t, input)
+	if err != nil {
+		t.Fatalf("go run failed: %s\n%s", err, out)
+	}
+	expected := "610\n7\n6\n[go, arv]\nnil\ndone\narv\ngo\nARV\n2-4-6\narv\n2\n4\n2\n2\nempty\nfalse\ntrue\nbig\n0\n9223372036854775808\nhi\nmonkey\n[]\nhello\nmonkey\n[1, 2]\n"
+	if out != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out)
+	}
+}
+
+func TestGeneratedProgramErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	// a let read before it runs is not found, as in the interpreter
+	out, err := runGenerated(t, "let f = fn() { x }; puts(f()); let x = 1;")
+	if err == nil || !strings.HasPrefix(out, "ERROR: identifier not found: x\n") {
+		t.Errorf("wrong error. got=%v\n%s", err, out)
+	}
+}
+
+// runGenerated generates the Go program of input and runs it, returning
+// its combined output
+func runGenerated(t *testing.T, input string) (string, error) {
+	src, err := Generate(parse(t, input), "main")
+	if err != nil {
+		t.Fatalf("Generate returned error: %s", err)
+	}
+
+	// the generated program must live inside the module to import the runtime
+	dir, err := os.MkdirTemp(".", "generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("go", "run", "./"+filepath.Base(dir)).CombinedOutput()
+	return string(out), err
+}
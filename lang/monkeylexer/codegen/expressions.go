@@ -0,0 +1,181 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/evaluator"
+)
+
+func (g *generator) expression(exp ast.Expression) (string, error) {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		if exp.Big != nil {
+			return fmt.Sprintf("runtime.BigInt(%q)", exp.Big.String()), nil
+		}
+		return fmt.Sprintf("runtime.Int(%d)", exp.Value), nil
+	case *ast.StringLiteral:
+		return fmt.Sprintf("runtime.String(%s)", strconv.Quote(exp.Value)), nil
+	case *ast.Boolean:
+		return fmt.Sprintf("runtime.Bool(%t)", exp.Value), nil
+	case *ast.Identifier:
+		if ok, hoisted := g.scope.resolve(exp.Value); ok {
+			if hoisted {
+				// a let read before it runs is not bound yet
+				return fmt.Sprintf("runtime.Bound(%s, %q)", goName(exp.Value), exp.Value), nil
+			}
+			return goName(exp.Value), nil
+		}
+		if _, ok := evaluator.LookupBuiltin(exp.Value); ok {
+			return fmt.Sprintf("runtime.Builtin(%q)", exp.Value), nil
+		}
+		return "", fmt.Errorf("codegen: identifier not found: %s", exp.Value)
+	case *ast.PrefixExpression:
+		right, err := g.expression(exp.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Prefix(%q, %s)", exp.Operator, right), nil
+	case *ast.InfixExpression:
+		if exp.Operator == "&&" || exp.Operator == "||" {
+			// the Go operator short circuits the right operand the same way
+			left, err := g.expression(exp.Left)
+			if err != nil {
+				return "", err
+			}
+			right, err := g.expression(exp.Right)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("runtime.Bool(runtime.Truthy(%s) %s runtime.Truthy(%s))", left, exp.Operator, right), nil
+		}
+		if exp.Operator == "|>" {
+			callee, arguments := exp.Right, []ast.Expression{}
+			if call, ok := exp.Right.(*ast.CallExpression); ok {
+				callee, arguments = call.Function, call.Arguments
+			}
+			operands, err := g.expressions(append([]ast.Expression{exp.Left, callee}, arguments...))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("runtime.Pipe(%s)", operands), nil
+		}
+		operands, err := g.expressions([]ast.Expression{exp.Left, exp.Right})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Infix(%q, %s)", exp.Operator, operands), nil
+	case *ast.IfExpression:
+		var out strings.Builder
+		g.ifDepth++
+		err := g.ifStatement(&out, exp, true)
+		g.ifDepth--
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("func() runtime.Object {\n%s}()", out.String()), nil
+	case *ast.ConditionalExpression:
+		// the branches are compiled into a closure so that only one runs
+		operands := []string{}
+		for _, operand := range []ast.Expression{exp.Condition, exp.Consequence, exp.Alternative} {
+			compiled, err := g.expression(operand)
+			if err != nil {
+				return "", err
+			}
+			operands = append(operands, compiled)
+		}
+		return fmt.Sprintf("func() runtime.Object {\nif runtime.Truthy(%s) {\nreturn %s\n}\nreturn %s\n}()",
+			operands[0], operands[1], operands[2]), nil
+	case *ast.FunctionLiteral:
+		ifDepth := g.ifDepth
+		g.ifDepth = 0
+		body, err := g.function(exp, exp.Body.Statements)
+		g.ifDepth = ifDepth
+		if err != nil {
+			return "", err
+		}
+		required := len(exp.Parameters)
+		for i, def := range exp.Defaults {
+			if def != nil {
+				required = i
+				break
+			}
+		}
+		maximum := len(exp.Parameters)
+		if exp.Rest != nil {
+			maximum = -1
+		}
+		if exp.Generator {
+			return fmt.Sprintf("runtime.Generator(%q, %d, %d, func(yield_ func(runtime.Object) bool, args ...runtime.Object) runtime.Object {\n%s})",
+				evaluator.FunctionName(exp.Name, exp.Token), required, maximum, body), nil
+		}
+		return fmt.Sprintf("runtime.Function(%q, %d, %d, func(args ...runtime.Object) runtime.Object {\n%s})",
+			evaluator.FunctionName(exp.Name, exp.Token), required, maximum, body), nil
+	case *ast.CallExpression:
+		fn, err := g.expression(exp.Function)
+		if err != nil {
+			return "", err
+		}
+		if len(exp.Arguments) == 0 {
+			return fmt.Sprintf("runtime.Call(%s)", fn), nil
+		}
+		args, err := g.expressions(exp.Arguments)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Call(%s, %s)", fn, args), nil
+	case *ast.TemplateLiteral:
+		parts, err := g.expressions(exp.Parts)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Template(%s)", parts), nil
+	case *ast.ArrayLiteral:
+		elements, err := g.expressions(exp.Elements)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Array(%s)", elements), nil
+	case *ast.IndexExpression:
+		operands, err := g.expressions([]ast.Expression{exp.Left, exp.Index})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Index(%s)", operands), nil
+	case *ast.MemberExpression:
+		object, err := g.expression(exp.Object)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Member(%s, %q)", object, exp.Property.Value), nil
+	case *ast.HashLiteral:
+		pairs := []ast.Expression{}
+		for _, key := range exp.Keys {
+			pairs = append(pairs, key, exp.Pairs[key])
+		}
+		compiled, err := g.expressions(pairs)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("runtime.Hash(%s)", compiled), nil
+	case nil:
+		return "", fmt.Errorf("codegen: missing expression")
+	default:
+		return "", fmt.Errorf("codegen: %T is not supported", exp)
+	}
+}
+
+// expressions compiles a comma separated list of expressions
+func (g *generator) expressions(exps []ast.Expression) (string, error) {
+	compiled := []string{}
+	for _, exp := range exps {
+		c, err := g.expression(exp)
+		if err != nil {
+			return "", err
+		}
+		compiled = append(compiled, c)
+	}
+	return strings.Join(compiled, ", "), nil
+}
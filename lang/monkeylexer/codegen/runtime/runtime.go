@@ -0,0 +1,210 @@
+// Package runtime is the support library of the Go source emitted by
+// codegen. Every operation delegates to the evaluator, so generated
+// programs behave like the interpreted ones, and runtime errors are raised
+// as panics that Main reports.
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/evaluator"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+type Object = value.Object
+
+// Main runs the generated program and reports its runtime error, if any
+func Main(run func() Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(*value.Error)
+			if !ok {
+				panic(r)
+			}
+			fmt.Fprintln(os.Stderr, err.Inspect())
+			os.Exit(1)
+		}
+	}()
+	run()
+}
+
+// check raises obj when it is an error
+func check(obj Object) Object {
+	if err, ok := obj.(*value.Error); ok {
+		panic(err)
+	}
+	return obj
+}
+
+func Int(v int64) Object {
+	return &value.Integer{Value: v}
+}
+
+// BigInt returns the integer of the decimal digits of a literal out of the
+// int64 range
+func BigInt(digits string) Object {
+	n, _ := new(big.Int).SetString(digits, 10)
+	return &value.BigInteger{Value: n}
+}
+
+func String(v string) Object {
+	return &value.String{Value: v}
+}
+
+func Bool(v bool) Object {
+	if v {
+		return evaluator.TRUE
+	}
+	return evaluator.FALSE
+}
+
+func Nil() Object {
+	return evaluator.NIL
+}
+
+// Truthy reports whether obj takes the consequence of an if expression
+func Truthy(obj Object) bool {
+	return evaluator.IsTruthy(obj)
+}
+
+func Infix(operator string, left, right Object) Object {
+	return check(evaluator.Infix(operator, left, right))
+}
+
+func Prefix(operator string, right Object) Object {
+	return check(evaluator.Prefix(operator, right))
+}
+
+func Index(left, index Object) Object {
+	return check(evaluator.Index(left, index))
+}
+
+func Member(object Object, name string) Object {
+	return check(evaluator.Member(object, name))
+}
+
+func Call(fn Object, args ...Object) Object {
+	return check(evaluator.Apply(fn, args))
+}
+
+// Pipe calls fn with piped followed by args, for piped |> fn(args)
+func Pipe(piped, fn Object, args ...Object) Object {
+	return check(evaluator.Apply(fn, append([]Object{piped}, args...)))
+}
+
+func Template(parts ...Object) Object {
+	return evaluator.Template(parts...)
+}
+
+func Array(elements ...Object) Object {
+	return &value.Array{Elements: elements}
+}
+
+// Hash builds a hash from alternating keys and values
+func Hash(pairs ...Object) Object {
+	hash := &value.Hash{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if !hash.Set(pairs[i], pairs[i+1]) {
+			panic(&value.Error{Message: fmt.Sprintf("unusable as hash key: %s", pairs[i].Type())})
+		}
+	}
+	return hash
+}
+
+// Builtin returns the builtin function bound to name
+func Builtin(name string) Object {
+	builtin, ok := evaluator.LookupBuiltin(name)
+	if !ok {
+		panic(&value.Error{Message: "identifier not found: " + name})
+	}
+	return builtin
+}
+
+// Bound returns the value of the let name, a let that has not run yet
+// holds nil and is not found as in the interpreter
+func Bound(obj Object, name string) Object {
+	if obj == nil {
+		panic(&value.Error{Message: "identifier not found: " + name})
+	}
+	return obj
+}
+
+// Function wraps a generated function body, it reports calls with a number
+// of arguments outside of minimum to maximum, -1 when variadic, instead of
+// indexing past args
+func Function(name string, minimum, maximum int, fn func(args ...Object) Object) Object {
+	return &value.Builtin{
+		Fn: func(args ...Object) Object {
+			if err := evaluator.CheckArity(name, minimum, maximum, len(args)); err != nil {
+				return err
+			}
+			return fn(args...)
+		},
+	}
+}
+
+// Generator is Function for a generator function, a call returns the
+// generator of the values fn passes to yield. The errors raised in fn
+// end the generator and are raised again where its values are read
+func Generator(name string, minimum, maximum int, fn func(yield func(Object) bool, args ...Object) Object) Object {
+	return Function(name, minimum, maximum, func(args ...Object) Object {
+		return value.NewGenerator(name, func(yield func(Object) bool) (result Object) {
+			defer func() {
+				if r := recover(); r != nil {
+					err, ok := r.(*value.Error)
+					if !ok {
+						panic(r)
+					}
+					result = err
+				}
+			}()
+			return fn(yield, args...)
+		})
+	})
+}
+
+// Iterator walks an iterable value in a for range loop
+type Iterator struct {
+	it    value.Iterator
+	value Object
+}
+
+// Iterate returns the iterator of a for range loop over obj
+func Iterate(obj Object) *Iterator {
+	it, ok := value.Iterate(obj)
+	if !ok {
+		panic(&value.Error{Message: fmt.Sprintf("cannot range over %s", obj.Type())})
+	}
+	return &Iterator{it: it}
+}
+
+// Next moves to the next element, it returns false past the last one
+func (i *Iterator) Next() bool {
+	val, ok := i.it.Next()
+	if !ok {
+		return false
+	}
+	i.value = check(val)
+	return true
+}
+
+// Value returns the current element
+func (i *Iterator) Value() Object {
+	return i.value
+}
+
+// Close stops a generator left before its end
+func (i *Iterator) Close() {
+	i.it.Close()
+}
+
+// Rest returns the arguments from index from on, for a ...rest parameter
+func Rest(args []Object, from int) Object {
+	rest := []Object{}
+	if len(args) > from {
+		rest = append(rest, args[from:]...)
+	}
+	return &value.Array{Elements: rest}
+}
@@ -0,0 +1,129 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+)
+
+// block compiles statements in order, when tail is set the value of the
+// last statement is returned like evalBlockStatement does
+func (g *generator) block(out *strings.Builder, statements []ast.Statement, tail bool) error {
+	for i, stmt := range statements {
+		if err := g.statement(out, stmt, tail && i == len(statements)-1); err != nil {
+			return err
+		}
+	}
+	if tail && len(statements) == 0 {
+		out.WriteString("return nil\n")
+	}
+	return nil
+}
+
+func (g *generator) statement(out *strings.Builder, stmt ast.Statement, tail bool) error {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		val, err := g.expression(stmt.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s = %s\n", goName(stmt.Name.Value), val)
+		if tail {
+			out.WriteString("return nil\n")
+		}
+	case *ast.ReturnStatement:
+		if g.ifDepth > 0 {
+			return fmt.Errorf("codegen: return inside an if expression is not supported")
+		}
+		val, err := g.expression(stmt.ReturnValue)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "return %s\n", val)
+	case *ast.YieldStatement:
+		if g.ifDepth > 0 {
+			return fmt.Errorf("codegen: yield inside an if expression is not supported")
+		}
+		val, err := g.expression(stmt.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "if !yield_(%s) {\nreturn runtime.Nil()\n}\n", val)
+		if tail {
+			out.WriteString("return nil\n")
+		}
+	case *ast.ExpressionStatement:
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+			return g.ifStatement(out, ie, tail)
+		}
+		if fe, ok := stmt.Expression.(*ast.ForExpression); ok {
+			return g.forStatement(out, fe, tail)
+		}
+		val, err := g.expression(stmt.Expression)
+		if err != nil {
+			return err
+		}
+		switch {
+		case tail:
+			fmt.Fprintf(out, "return %s\n", val)
+		case isCall(stmt.Expression):
+			fmt.Fprintf(out, "%s\n", val)
+		default:
+			fmt.Fprintf(out, "_ = %s\n", val)
+		}
+	default:
+		return fmt.Errorf("codegen: %T is not supported", stmt)
+	}
+	return nil
+}
+
+// ifStatement compiles an if expression whose value is either returned,
+// when tail is set, or discarded
+func (g *generator) ifStatement(out *strings.Builder, ie *ast.IfExpression, tail bool) error {
+	cond, err := g.expression(ie.Condition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "if runtime.Truthy(%s) {\n", cond)
+	if err := g.block(out, ie.Consequence.Statements, tail); err != nil {
+		return err
+	}
+	out.WriteString("}")
+	if ie.Alternative != nil {
+		out.WriteString(" else {\n")
+		if err := g.block(out, ie.Alternative.Statements, tail); err != nil {
+			return err
+		}
+		out.WriteString("}")
+	}
+	out.WriteString("\n")
+	if tail && ie.Alternative == nil {
+		out.WriteString("return runtime.Nil()\n")
+	}
+	return nil
+}
+
+// forStatement compiles a for range loop, the iterator is closed when the
+// function returns so a generator left early by a return is stopped
+func (g *generator) forStatement(out *strings.Builder, fe *ast.ForExpression, tail bool) error {
+	iterable, err := g.expression(fe.Iterable)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "{\nit_ := runtime.Iterate(%s)\ndefer it_.Close()\nfor it_.Next() {\n", iterable)
+	fmt.Fprintf(out, "%s = it_.Value()\n", goName(fe.Variable.Value))
+	if err := g.block(out, fe.Body.Statements, false); err != nil {
+		return err
+	}
+	out.WriteString("}\n}\n")
+	if tail {
+		out.WriteString("return runtime.Nil()\n")
+	}
+	return nil
+}
+
+func isCall(exp ast.Expression) bool {
+	_, ok := exp.(*ast.CallExpression)
+	return ok
+}
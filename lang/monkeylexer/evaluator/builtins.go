@@ -2,11 +2,13 @@ package evaluator
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/delavalom/arvlang/lang/monkeylexer/value"
 )
 
 var builtins = map[string]*value.Builtin{
+	// len counts the characters (runes) of a string, not its bytes
 	"len": {
 		Fn: func(args ...value.Object) value.Object {
 			if len(args) != 1 {
@@ -14,7 +16,7 @@ var builtins = map[string]*value.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *value.String:
-				return &value.Integer{Value: int64(len(arg.Value))}
+				return &value.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -53,6 +55,35 @@ var builtins = map[string]*value.Builtin{
 			return &value.Array{Elements: newElements}
 		},
 	},
+	// next resumes a generator and returns its next value, nil once it is done
+	"next": {
+		Fn: func(args ...value.Object) value.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			generator, ok := args[0].(*value.Generator)
+			if !ok {
+				return newError("argument to `next` must be GENERATOR, got %s", args[0].Type())
+			}
+			if val, ok := generator.Next(); ok {
+				return val
+			}
+			return NIL
+		},
+	},
+	"close": {
+		Fn: func(args ...value.Object) value.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			generator, ok := args[0].(*value.Generator)
+			if !ok {
+				return newError("argument to `close` must be GENERATOR, got %s", args[0].Type())
+			}
+			generator.Close()
+			return NIL
+		},
+	},
 	"puts": {
 		Fn: func(args ...value.Object) value.Object {
 			for _, arg := range args {
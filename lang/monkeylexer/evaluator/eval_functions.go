@@ -1,6 +1,9 @@
 package evaluator
 
 import (
+	"math"
+	"math/big"
+
 	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
 	"github.com/delavalom/arvlang/lang/monkeylexer/value"
 )
@@ -60,26 +63,58 @@ func evalPrefixExpression(operator string, right value.Object) value.Object {
 // this functions compares the right value and returns the opposite value
 // it takes as input a value.Object and returns a value.Object
 func evalBangOperatorExpression(right value.Object) value.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NIL:
-		return TRUE
+	return nativeBoolToBooleanObject(!IsTruthy(right))
+}
+
+// IsTruthy reports whether obj counts as true where a condition is expected,
+// in if expressions, ! and the logical operators. false, nil, 0, "", [] and
+// {} are falsy, every other value is truthy
+func IsTruthy(obj value.Object) bool {
+	switch obj := obj.(type) {
+	case *value.Boolean:
+		return obj.Value
+	case *value.Nil:
+		return false
+	case *value.Integer:
+		return obj.Value != 0
+	case *value.String:
+		return obj.Value != ""
+	case *value.Array:
+		return len(obj.Elements) != 0
+	case *value.Hash:
+		return obj.Len() != 0
 	default:
-		return FALSE
+		return true
 	}
 }
 
+// evalLogicalExpression evaluates && and || from the already evaluated left
+// operand, the right one is only evaluated when it decides the result
+func evalLogicalExpression(node *ast.InfixExpression, left value.Object, env *value.Environment) value.Object {
+	if IsTruthy(left) == (node.Operator == "||") {
+		return nativeBoolToBooleanObject(IsTruthy(left))
+	}
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	return nativeBoolToBooleanObject(IsTruthy(right))
+}
+
 // evalMinusPrefixOperatorExpression evaluates a minus prefix operator expression value from the value system
 // this functions compares the right value and returns the negative value
 func evalMinusPrefixOperatorExpression(right value.Object, operator string) value.Object {
-	if right.Type() != value.INTEGER_VAL {
+	switch right := right.(type) {
+	case *value.Integer:
+		if right.Value == math.MinInt64 {
+			return value.NewInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &value.Integer{Value: -right.Value}
+	case *value.BigInteger:
+		return value.NewInteger(new(big.Int).Neg(right.Value))
+	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
-	val := right.(*value.Integer).Value
-	return &value.Integer{Value: -val}
 }
 
 // evalInfixExpression evaluates an infix expression value from the value system
@@ -91,12 +126,14 @@ func evalInfixExpression(operator string,
 	switch {
 	case left.Type() == value.INTEGER_VAL && right.Type() == value.INTEGER_VAL:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntegerInfixExpression(operator, toBigInt(left), toBigInt(right))
 	case left.Type() == value.STRING_VAL && right.Type() == value.STRING_VAL:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(value.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!value.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
@@ -116,12 +153,30 @@ func evalIntegerInfixExpression(operator string,
 	rightVal := right.(*value.Integer).Value
 	switch operator {
 	case "+":
-		return &value.Integer{Value: leftVal + rightVal}
+		sum := leftVal + rightVal
+		if (leftVal > 0 && rightVal > 0 && sum < 0) || (leftVal < 0 && rightVal < 0 && sum >= 0) {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+		}
+		return &value.Integer{Value: sum}
 	case "-":
-		return &value.Integer{Value: leftVal - rightVal}
+		diff := leftVal - rightVal
+		if (leftVal >= 0 && rightVal < 0 && diff < 0) || (leftVal < 0 && rightVal > 0 && diff >= 0) {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+		}
+		return &value.Integer{Value: diff}
 	case "*":
-		return &value.Integer{Value: leftVal * rightVal}
+		product := leftVal * rightVal
+		if leftVal != 0 && (product/leftVal != rightVal || (leftVal == -1 && rightVal == math.MinInt64)) {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+		}
+		return &value.Integer{Value: product}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+		}
 		return &value.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -137,18 +192,91 @@ func evalIntegerInfixExpression(operator string,
 	}
 }
 
+// evalBigIntegerInfixExpression evaluates an integer infix expression with
+// arbitrary precision, it is used when an operand is a value.BigInteger or
+// when the int64 operation overflows, results that fit an int64 are
+// returned as value.Integer
+func evalBigIntegerInfixExpression(operator string, leftVal, rightVal *big.Int) value.Object {
+	switch operator {
+	case "+":
+		return value.NewInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return value.NewInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return value.NewInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return value.NewInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %s %s %s",
+			value.BIG_INTEGER_VAL, operator, value.BIG_INTEGER_VAL)
+	}
+}
+
+// isInteger checks if the given value is a value.Integer or a value.BigInteger
+func isInteger(obj value.Object) bool {
+	return obj.Type() == value.INTEGER_VAL || obj.Type() == value.BIG_INTEGER_VAL
+}
+
+// toBigInt converts a value.Integer or a value.BigInteger to a big.Int
+func toBigInt(obj value.Object) *big.Int {
+	if i, ok := obj.(*value.Integer); ok {
+		return big.NewInt(i.Value)
+	}
+	return obj.(*value.BigInteger).Value
+}
+
 // evalStringInfixExpression evaluates a string infix expression value from the value system
 // this functions compares the operator and calls the corresponding function
 // to evaluate the expression, it takes as input an operator and two value.Objects
 func evalStringInfixExpression(operator string,
 	left, right value.Object,
 ) value.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
 	leftVal := left.(*value.String).Value
 	rightVal := right.(*value.String).Value
-	return &value.String{Value: leftVal + rightVal}
+	switch operator {
+	case "+":
+		return &value.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalPipeExpression evaluates x |> f(y) as f(x, y), the piped value goes
+// first in the arguments of a call and is the only argument of any other
+// function, as in x |> f
+func evalPipeExpression(right ast.Expression, piped value.Object, env *value.Environment) value.Object {
+	callee, arguments := right, []ast.Expression(nil)
+	if call, ok := right.(*ast.CallExpression); ok {
+		callee, arguments = call.Function, call.Arguments
+	}
+	function := Eval(callee, env)
+	if isError(function) {
+		return function
+	}
+	args := evalExpressions(arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	return applyFunction(function, append([]value.Object{piped}, args...))
 }
 
 // evalIfExpression evaluates an if expression value from the value system
@@ -159,7 +287,7 @@ func evalIfExpression(ie *ast.IfExpression, env *value.Environment) value.Object
 	if isError(condition) {
 		return condition
 	}
-	if condition == TRUE {
+	if IsTruthy(condition) {
 		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
@@ -168,6 +296,19 @@ func evalIfExpression(ie *ast.IfExpression, env *value.Environment) value.Object
 	}
 }
 
+// evalConditionalExpression evaluates c ? a : b, only the branch chosen by
+// the truthiness of the condition is evaluated
+func evalConditionalExpression(ce *ast.ConditionalExpression, env *value.Environment) value.Object {
+	condition := Eval(ce.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+	if IsTruthy(condition) {
+		return Eval(ce.Consequence, env)
+	}
+	return Eval(ce.Alternative, env)
+}
+
 // evalIdentifier evaluates an identifier value from the value system
 // this functions compares the identifier and returns the value of the identifier
 // it takes as input an identifier and an environment
@@ -206,11 +347,34 @@ func evalIndexExpression(left, index value.Object) value.Object {
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == value.HASH_VAL:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == value.MODULE_VAL:
+		return evalModuleIndexExpression(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
 }
 
+// evalMemberExpression evaluates a member access on a hash or a module,
+// a missing field is an error while a field holding nil gives nil. The
+// methods of the type of object come after the fields, bound to object
+func evalMemberExpression(object value.Object, name string) value.Object {
+	switch object := object.(type) {
+	case *value.Hash:
+		if val, ok := object.Get(&value.String{Value: name}); ok {
+			return val
+		}
+	case *value.Module:
+		return evalModuleIndexExpression(object, &value.String{Value: name})
+	}
+	if method, ok := lookupMethod(object, name); ok {
+		return method
+	}
+	if object.Type() == value.HASH_VAL {
+		return newError("no such field: %s", name)
+	}
+	return newError("no method %s on %s", name, object.Type())
+}
+
 // evalArrayIndexExpression evaluates an array index expression value from the value system
 // this functions compares the array and index and returns the value of the index
 func evalArrayIndexExpression(array, index value.Object) value.Object {
@@ -228,37 +392,35 @@ func evalArrayIndexExpression(array, index value.Object) value.Object {
 func evalHashLiteral(
 	node *ast.HashLiteral, env *value.Environment,
 ) value.Object {
-	pairs := make(map[value.HashKey]value.HashPair)
-	for keyNode, valueNode := range node.Pairs {
+	hash := &value.Hash{}
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
-		hashKey, ok := key.(value.Hashable)
-		if !ok {
+		if _, ok := value.HashKeyOf(key); !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 		val := Eval(valueNode, env)
 		if isError(val) {
 			return val
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = value.HashPair{Key: key, Value: val}
+		hash.Set(key, val)
 	}
-	return &value.Hash{Pairs: pairs}
+	return hash
 }
 
 // evalHashIndexExpression evaluates a hash index expression value from the value system
 // this functions compares the hash and index and returns the value of the index
 func evalHashIndexExpression(hash, index value.Object) value.Object {
 	hashObject := hash.(*value.Hash)
-	key, ok := index.(value.Hashable)
-	if !ok {
+	if _, ok := value.HashKeyOf(index); !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	val, ok := hashObject.Get(index)
 	if !ok {
 		return NIL
 	}
-	return pair.Value
+	return val
 }
@@ -2,8 +2,13 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
 	"github.com/delavalom/arvlang/lang/monkeylexer/value"
 )
 
@@ -20,6 +25,9 @@ func Eval(node ast.Node, env *value.Environment) value.Object {
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &value.BigInteger{Value: node.Big}
+		}
 		return &value.Integer{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
@@ -34,21 +42,37 @@ func Eval(node ast.Node, env *value.Environment) value.Object {
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return locate(evalPrefixExpression(node.Operator, right), node.Token, env)
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, left, env)
+		}
+		if node.Operator == "|>" {
+			return locate(evalPipeExpression(node.Right, left, env), node.Token, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return locate(evalInfixExpression(node.Operator, left, right), node.Token, env)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+	case *ast.YieldStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return evalYield(val, env)
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
@@ -56,11 +80,18 @@ func Eval(node ast.Node, env *value.Environment) value.Object {
 		}
 		env.Set(node.Name.Value, val)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return locate(evalIdentifier(node, env), node.Token, env)
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &value.Function{Parameters: params, Env: env, Body: body}
+		return &value.Function{
+			Parameters: node.Parameters,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Env:        env,
+			Body:       node.Body,
+			Name:       node.Name,
+			Token:      node.Token,
+			Generator:  node.Generator,
+		}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
 		if isError(function) {
@@ -70,7 +101,7 @@ func Eval(node ast.Node, env *value.Environment) value.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return locate(applyFunction(function, args), node.Token, env)
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -86,11 +117,25 @@ func Eval(node ast.Node, env *value.Environment) value.Object {
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return locate(evalIndexExpression(left, index), node.Token, env)
+	case *ast.MemberExpression:
+		object := Eval(node.Object, env)
+		if isError(object) {
+			return object
+		}
+		return locate(evalMemberExpression(object, node.Property.Value), node.Token, env)
 	case *ast.StringLiteral:
 		return &value.String{Value: node.Value}
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.ImportExpression:
+		return locate(evalImportExpression(node, env), node.Token, env)
+	case *ast.TemplateLiteral:
+		parts := evalExpressions(node.Parts, env)
+		if len(parts) == 1 && isError(parts[0]) {
+			return parts[0]
+		}
+		return Template(parts...)
 	}
 
 	return nil
@@ -99,7 +144,13 @@ func Eval(node ast.Node, env *value.Environment) value.Object {
 func applyFunction(fn value.Object, args []value.Object) value.Object {
 	switch fn := fn.(type) {
 	case *value.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
+		if fn.Generator {
+			return newGenerator(fn, extendedEnv)
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *value.Builtin:
@@ -109,13 +160,120 @@ func applyFunction(fn value.Object, args []value.Object) value.Object {
 	}
 }
 
-func extendFunctionEnv(fn *value.Function, args []value.Object,
-) *value.Environment {
+// Infix, Prefix, Index, Member and Apply expose the evaluator operations on already
+// evaluated values, so generated code shares the semantics of the interpreter
+
+func Infix(operator string, left, right value.Object) value.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+func Prefix(operator string, right value.Object) value.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+func Index(left, index value.Object) value.Object {
+	return evalIndexExpression(left, index)
+}
+
+func Member(object value.Object, name string) value.Object {
+	return evalMemberExpression(object, name)
+}
+
+func Apply(fn value.Object, args []value.Object) value.Object {
+	return applyFunction(fn, args)
+}
+
+// Template joins the evaluated parts of a template literal, strings are
+// inserted as they are and any other value as its Inspect form
+func Template(parts ...value.Object) value.Object {
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteString(part.Inspect())
+	}
+	return &value.String{Value: out.String()}
+}
+
+// LookupBuiltin returns the builtin function bound to name
+func LookupBuiltin(name string) (*value.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// BuiltinNames returns the sorted names of the builtin functions
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// extendFunctionEnv binds the parameters of fn to args, the missing
+// arguments take their default value, evaluated after the parameters before
+// them are bound, and the extra ones are collected in the ...rest parameter
+func extendFunctionEnv(fn *value.Function, args []value.Object) (*value.Environment, value.Object) {
+	required := len(fn.Parameters)
+	for i := range fn.Parameters {
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			required = i
+			break
+		}
+	}
+	maximum := len(fn.Parameters)
+	if fn.Rest != nil {
+		maximum = -1
+	}
+	if err := CheckArity(FunctionName(fn.Name, fn.Token), required, maximum, len(args)); err != nil {
+		return nil, err
+	}
+
 	env := value.NewEnclosedEnvironment(fn.Env)
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+		def := Eval(fn.Defaults[paramIdx], env)
+		if isError(def) {
+			return nil, def
+		}
+		env.Set(param.Value, def)
+	}
+	if fn.Rest != nil {
+		rest := []value.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &value.Array{Elements: rest})
+	}
+	return env, nil
+}
+
+// FunctionName describes the function defined at tok for errors,
+// name is empty for anonymous functions
+func FunctionName(name string, tok token.Token) string {
+	if name == "" {
+		name = "fn"
 	}
-	return env
+	return fmt.Sprintf("%s (defined at %d:%d)", name, tok.Line, tok.Column)
+}
+
+// CheckArity returns an error when a function taking minimum to maximum
+// arguments, or any number above minimum when maximum is -1, is called
+// with got arguments
+func CheckArity(function string, minimum, maximum, got int) *value.Error {
+	if got >= minimum && (maximum < 0 || got <= maximum) {
+		return nil
+	}
+	want := fmt.Sprintf("%d to %d", minimum, maximum)
+	switch {
+	case maximum < 0:
+		want = fmt.Sprintf("at least %d", minimum)
+	case minimum == maximum:
+		want = fmt.Sprint(minimum)
+	}
+	return newError("wrong number of arguments for %s. got=%d, want=%s", function, got, want)
 }
 func unwrapReturnValue(obj value.Object) value.Object {
 	if returnValue, ok := obj.(*value.ReturnValue); ok {
@@ -135,6 +293,19 @@ func newError(format string, a ...interface{}) *value.Error {
 	return &value.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// locate sets the position of tok on obj when it is an error without one,
+// so an error points at the innermost expression that raised it
+func locate(obj value.Object, tok token.Token, env *value.Environment) value.Object {
+	if err, ok := obj.(*value.Error); ok && err.Span.IsZero() {
+		err.Span = diagnostic.Span{
+			File:  env.File(),
+			Start: diagnostic.Position{Line: tok.Line, Column: tok.Column},
+			End:   diagnostic.Position{Line: tok.Line, Column: tok.Column + utf8.RuneCountInString(tok.Literal)},
+		}
+	}
+	return obj
+}
+
 func isError(obj value.Object) bool {
 	if obj != nil {
 		return obj.Type() == value.ERROR_VAL
@@ -29,6 +29,10 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"0xFF", 255},
+		{"0o17 + 0b1010", 25},
+		{"1_000_000", 1000000},
+		{"0xdead_beef", 0xdeadbeef},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -36,6 +40,74 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestLeadingZeroIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"010", "decimal literal 010 has a leading zero, octal literals start with 0o at 1:1"},
+		{"09", "decimal literal 09 has a leading zero, octal literals start with 0o at 1:1"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) != 1 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q - wrong errors. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+		if evaluated, ok := testEval(tt.input).(*value.Integer); ok {
+			t.Errorf("%q - evaluated to %d", tt.input, evaluated.Value)
+		}
+	}
+}
+
+func TestBigIntegerPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"0 - (-9223372036854775807 - 1)", "9223372036854775808"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"let f = fn(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; f(25)", "15511210043330985984000000"},
+		{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"(9223372036854775807 * 4) / 4", 9223372036854775807},
+		{"(9223372036854775807 + 1) > 9223372036854775807", true},
+		{"9223372036854775807 < (9223372036854775807 + 1)", true},
+		{"(9223372036854775807 + 1) == (9223372036854775807 + 1)", true},
+		{"(9223372036854775807 + 1) != 5", true},
+		{"let big = 9223372036854775807 * 2; {big: 1}[9223372036854775807 * 2]", 1},
+		{"(9223372036854775807 + 1) / 0", "division by zero"},
+		// a literal out of the int64 range is read as the value it inspects as
+		{"9223372036854775808", "9223372036854775808"},
+		{"15511210043330985984000000 / 25", "620448401733239439360000"},
+		{"0x1_0000_0000_0000_0000", "18446744073709551616"},
+		{"-9223372036854775808", -9223372036854775808},
+		{"9223372036854775808 - 1", 9223372036854775807},
+		{"9223372036854775808 == 9223372036854775807 + 1", true},
+		{"1 / 0", "division by zero"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if big, ok := evaluated.(*value.BigInteger); ok {
+				if big.Inspect() != expected {
+					t.Errorf("BigInteger has wrong value. got=%s, want=%s", big.Inspect(), expected)
+				}
+				continue
+			}
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+}
+
 func testEval(input string) value.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -83,6 +155,26 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{`"apple" < "banana"`, true},
+		{`"b" > "abc"`, true},
+		{`"ab" < "a"`, false},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] != [1, 2, 3]", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{"{} == {}", true},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"9223372036854775807 + 1 == 1 + 9223372036854775807", true},
+		{"9223372036854775807 + 1 - 1 == 9223372036854775807", true},
+		{`1 == "1"`, false},
+		{"[1] == {}", false},
+		{"len == len", true},
+		{"fn() {} == fn() {}", false},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -115,11 +207,74 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{"!0", true},
+		{"!if (false) { 1 }", true},
+		{"!\"\"", true},
+		{"!\"a\"", false},
+		{"![]", true},
+		{"![0]", false},
+		{"!{}", true},
+		{"!{1: 2}", false},
+		{"!fn() {}", false},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && \"a\"", true},
+		{"[] || {}", false},
+		{"0 || [0]", true},
+		{"1 < 2 && 2 < 3", true},
+		// the right operand is not evaluated when the left one decides
+		{"false && missing", false},
+		{"true || missing", true},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
+
+	testErrorObject(t, testEval("true && missing"), "identifier not found: missing")
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+		{"let double = fn(x) { x * 2 }; 3 |> double", 6},
+		{"let double = fn(x) { x * 2 }; 3 |> double()", 6},
+		{"let add = fn(a, b) { a + b }; 1 + 2 |> add(10) |> add(100)", 113},
+		{"[1, 2, 3] |> last", 3},
+		{`"a" |> fn(s, t) { s + t }("b") |> len`, 2},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3) == 7", true},
+		{"1 |> 2", "not a function: INTEGER"},
+		{"1 |> fn(a, b) { a }()", "wrong number of arguments for fn (defined at 1:6). got=1, want=2"},
+		{"1 |> missing(2)", "identifier not found: missing"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
 }
 
 func TestIfElseExpressions(t *testing.T) {
@@ -133,6 +288,13 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1) { 10 } else { 20 }", 10},
+		{"if (0) { 10 } else { 20 }", 20},
+		{"if (\"\") { 10 } else { 20 }", 20},
+		{"if ([]) { 10 } else { 20 }", 20},
+		{"if ({}) { 10 } else { 20 }", 20},
+		{"if ([1]) { 10 } else { 20 }", 10},
+		{"if (len) { 10 } else { 20 }", 10},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -145,6 +307,37 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestConditionalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 10 : 20", 10},
+		{"false ? 10 : 20", 20},
+		{"1 < 2 ? 10 : 20", 10},
+		{"[] ? 10 : 20", 20},
+		{`"a" ? 10 : 20`, 10},
+		{"let x = 5; x > 3 ? x * 2 : x", 10},
+		{"false ? 1 : true ? 2 : 3", 2},
+		{"let max = fn(a, b) { a > b ? a : b }; max(3, 7)", 7},
+		{`{true ? "a" : "b": 1}["a"]`, 1},
+		// the branch that is not chosen is not evaluated
+		{"true ? 1 : missing", 1},
+		{"false ? missing : 2", 2},
+		{"missing ? 1 : 2", "identifier not found: missing"},
+		{"true ? -true : 2", "unknown operator: -BOOLEAN"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+}
+
 func testNullObject(t *testing.T, obj value.Object) bool {
 	if obj != NIL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -224,6 +417,10 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[1, fn(x) { x }]: "Monkey"}`,
+			"unusable as hash key: ARRAY",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -241,6 +438,74 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestFunctionArity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let add = fn(a, b) { a + b };\nadd(1)", "wrong number of arguments for add (defined at 1:11). got=1, want=2"},
+		{"fn(a) { a }(1, 2)", "wrong number of arguments for fn (defined at 1:1). got=2, want=1"},
+		{"let f = fn(a, b = 1) { a };\nf()", "wrong number of arguments for f (defined at 1:9). got=0, want=1 to 2"},
+		{"let f = fn(a, ...rest) { a };\nf()", "wrong number of arguments for f (defined at 1:9). got=0, want=at least 1"},
+	}
+
+	for i, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+		if err, ok := testEval(tt.input).(*value.Error); ok && err.Span.IsZero() {
+			t.Errorf("tests[%d] - arity error has no position", i)
+		}
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(a, b = 10) { a + b }; f(1)", 11},
+		{"let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+		{"let f = fn(a, b = a * 2) { b }; f(4)", 8},
+		{"let f = fn(...rest) { rest }; last(f())", nil},
+		{"let f = fn(a, ...rest) { rest }; f(1, 2, 3)[1]", 3},
+		{"let f = fn(a, b = 2, ...rest) { b + rest[0] }; f(1, 3, 4)", 7},
+		{"let f = fn(a = nope) { a }; f(5)", 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if integer, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	testErrorObject(t, testEval("let f = fn(a = nope) { a }; f()"), "identifier not found: nope")
+}
+
+func TestErrorSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "1:3"},
+		{"let x = 1;\nlet y = x / 0;", "2:11"},
+		{"let f = fn(a) { a - true };\nf(1);", "1:19"},
+		{"[1, 2][\"a\"]", "1:7"},
+		{"foobar", "1:1"},
+	}
+
+	for i, tt := range tests {
+		err, ok := testEval(tt.input).(*value.Error)
+		if !ok {
+			t.Fatalf("tests[%d] - no error object returned", i)
+		}
+		if err.Span.String() != tt.expected {
+			t.Errorf("tests[%d] - wrong span. expected=%s, got=%s", i, tt.expected, err.Span)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -318,6 +583,31 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestTemplateLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`plain`", "plain"},
+		{"let name = \"arv\"; `Hello ${name}!`", "Hello arv!"},
+		{"let x = 2; `${x} * ${x} = ${x * x}`", "2 * 2 = 4"},
+		{"`${[1, true]} ${ {\"a\": 1}[\"a\"] }`", "[1, true] 1"},
+		{"let f = fn(n) { `<${n}>` }; `line 1\n${f(`inner`)}`", "line 1\n<inner>"},
+		{"let n = 1; `a ${ `{` } b`", "a { b"},
+		{"`${ `in ${ `}` } ${1}` }!`", "in } 1!"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*value.String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -326,6 +616,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("café")`, 4},
+		{`len("😀")`, 1},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
@@ -451,23 +743,25 @@ func TestHashLiterals(t *testing.T) {
 	if !ok {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
-	expected := map[value.HashKey]int64{
-		(&value.String{Value: "one"}).HashKey():   1,
-		(&value.String{Value: "two"}).HashKey():   2,
-		(&value.String{Value: "three"}).HashKey(): 3,
-		(&value.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                            5,
-		FALSE.HashKey():                           6,
-	}
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
-	}
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
-		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+	expected := []struct {
+		key   value.Object
+		value int64
+	}{
+		{&value.String{Value: "one"}, 1},
+		{&value.String{Value: "two"}, 2},
+		{&value.String{Value: "three"}, 3},
+		{&value.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+	}
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
+	}
+	for i, pair := range result.Pairs() {
+		if !value.Equal(pair.Key, expected[i].key) {
+			t.Errorf("pairs[%d] has wrong key. want=%s, got=%s", i, expected[i].key.Inspect(), pair.Key.Inspect())
 		}
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, pair.Value, expected[i].value)
 	}
 }
 
@@ -504,6 +798,26 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{[1, 2]: 5}[[1, 2]]`,
+			5,
+		},
+		{
+			`let x = 1; {[x, [x + 1, "a"]]: 5}[[1, [2, "a"]]]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[2, 1]]`,
+			nil,
+		},
+		{
+			`{[]: 5}[[]]`,
+			5,
+		},
+		{
+			`{[1]: 5}[1]`,
+			nil,
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -515,3 +829,40 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"port": 80}.port`, 80},
+		{`let config = {"server": {"port": 80}}; config.server.port`, 80},
+		{`let h = {"f": fn(x) { x * 2 }}; h.f(2)`, 4},
+		{`{"none": if (false) { 1 }}.none`, nil},
+		{`{"port": 80}.host`, "no such field: host"},
+		{`{1: 80}.port`, "no such field: port"},
+		{`[1].length`, "no method length on ARRAY"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testErrorObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashIndexCollision(t *testing.T) {
+	// every key collides under this hasher
+	hash := &value.Hash{Hasher: func(value.Object) (value.HashKey, bool) { return value.HashKey{}, true }}
+	hash.Set(&value.String{Value: "one"}, &value.Integer{Value: 1})
+	hash.Set(&value.String{Value: "other"}, &value.Integer{Value: 2})
+
+	testIntegerObject(t, evalHashIndexExpression(hash, &value.String{Value: "one"}), 1)
+	testIntegerObject(t, evalHashIndexExpression(hash, &value.String{Value: "other"}), 2)
+	testNullObject(t, evalHashIndexExpression(hash, &value.String{Value: "none"}))
+}
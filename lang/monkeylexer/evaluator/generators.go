@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+// newGenerator returns the generator of a call to fn, its body runs in env
+// when the first value is asked for and the session of env stops it if it
+// is still suspended when the session is closed
+func newGenerator(fn *value.Function, env *value.Environment) *value.Generator {
+	generator := value.NewGenerator(FunctionName(fn.Name, fn.Token), func(yield func(value.Object) bool) value.Object {
+		env.SetYield(yield)
+		return unwrapReturnValue(Eval(fn.Body, env))
+	})
+	env.Session().Track(generator)
+	return generator
+}
+
+// evalYield sends val out of the generator call env is for, a closed
+// generator returns from its function so the body stops there
+func evalYield(val value.Object, env *value.Environment) value.Object {
+	yield := env.Yield()
+	if yield == nil {
+		return newError("yield outside of a generator")
+	}
+	if !yield(val) {
+		return &value.ReturnValue{Value: NIL}
+	}
+	return NIL
+}
+
+// evalForExpression runs the body of a for range loop for each element of
+// the iterable, a generator left early by a return or an error is closed
+func evalForExpression(node *ast.ForExpression, env *value.Environment) value.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	it, ok := value.Iterate(iterable)
+	if !ok {
+		return locate(newError("cannot range over %s", iterable.Type()), node.Token, env)
+	}
+	defer it.Close()
+
+	for {
+		element, ok := it.Next()
+		if !ok {
+			return NIL
+		}
+		if isError(element) {
+			return element
+		}
+		env.Set(node.Variable.Value, element)
+		result := Eval(node.Body, env)
+		if result != nil {
+			rt := result.Type()
+			if rt == value.RETURN_VALUE_VAL || rt == value.ERROR_VAL {
+				return result
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+func TestForExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for x range [1, 2, 3] { let sum = sum + x; }; sum", 6},
+		{`let s = ""; for c range "héllo" { let s = c + s; }; s`, "olléh"},
+		{`let s = ""; for k range {"a": 1, "b": 2} { let s = s + k; }; s`, "ab"},
+		{"let n = 0; for x range [] { let n = 1; }; n", 0},
+		{"for x range [1] { x }", nil},
+		{"let f = fn() { for x range [1, 2, 3] { if (x == 2) { return x * 10; } } }; f()", 20},
+		{"for x range 5 {}", "cannot range over INTEGER"},
+		{"for x range [1, 2] { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tt := range tests {
+		testGeneratorResult(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let g = fn() { yield 1; yield 2; }; let s = 0; for x range g() { let s = s * 10 + x; }; s", 12},
+		{"let g = fn(xs) { for x range xs { yield x * x; } }; let s = []; for x range g([1, 2, 3]) { let s = push(s, x); }; s[2]", 9},
+		{"let g = fn() { yield 1; yield 2; }; let it = g(); next(it); next(it)", 2},
+		{"let g = fn() { yield 1; }; let it = g(); next(it); next(it)", nil},
+		{"let g = fn() { yield 1; return 5; yield 2; }; let it = g(); next(it); next(it)", nil},
+		{"let from = fn(n) { yield n; for x range from(n + 1) { yield x; } }; let take = fn(g, n) { for x range g { if (n == 0) { return 0; } yield x; let n = n - 1; } }; let s = 0; for x range take(from(1), 3) { let s = s + x; }; s", 6},
+		{"let g = fn() { yield 1; yield missing; }; let it = g(); next(it); next(it)", "identifier not found: missing"},
+		{"let g = fn() { yield 1; yield missing; }; for x range g() {}", "identifier not found: missing"},
+		{"let g = fn(a) { yield a; }; g()", "wrong number of arguments for g (defined at 1:9). got=0, want=1"},
+		{"let g = fn() { yield 1; yield 2; }; let it = g(); next(it); close(it); next(it)", nil},
+		{"let g = fn() { yield 1; }; let it = g(); close(it); next(it)", nil},
+		{"next([1])", "argument to `next` must be GENERATOR, got ARRAY"},
+		{"close(1)", "argument to `close` must be GENERATOR, got INTEGER"},
+		{"fn() { yield 1; }()", "<generator fn (defined at 1:1)>"},
+	}
+	for _, tt := range tests {
+		testGeneratorResult(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestGeneratorLeftEarly(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	input := `
+let naturals = fn() { let from = fn(n) { yield n; for x range from(n + 1) { yield x; } }; from(0) };
+let first = fn(g, n) { for x range g { if (x == n) { return x; } } };
+first(naturals(), 5) + first(naturals(), 10)`
+	testIntegerObject(t, testEval(input), 15)
+
+	// the generators stopped by the returns leave no goroutine behind
+	if after := waitGoroutines(before); after > before {
+		t.Errorf("generator goroutines leaked. before=%d, after=%d", before, after)
+	}
+}
+
+func TestGeneratorLeftSuspended(t *testing.T) {
+	tests := []string{
+		"let three = fn() { yield 1; yield 2; yield 3; }; let g = three(); next(g); 5",
+		"let inner = fn() { yield 1; yield 2; }; let outer = fn() { for x range inner() { yield x; } }; let g = outer(); next(g); 5",
+	}
+
+	for i, input := range tests {
+		before := runtime.NumGoroutine()
+		env := value.NewEnvironment()
+		program := parser.New(lexer.New(input)).ParseProgram()
+		testIntegerObject(t, Eval(program, env), 5)
+
+		// the generators bound with let are still suspended
+		if running := runtime.NumGoroutine(); running == before {
+			t.Fatalf("tests[%d] - no generator goroutine is running", i)
+		}
+		env.Session().Close()
+		if after := waitGoroutines(before); after > before {
+			t.Errorf("tests[%d] - closing the session leaked goroutines. before=%d, after=%d", i, before, after)
+		}
+	}
+}
+
+// waitGoroutines waits up to a second for the number of goroutines to go
+// down to want and returns the number left
+func waitGoroutines(want int) int {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func testGeneratorResult(t *testing.T, obj value.Object, expected interface{}) {
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, obj, int64(expected))
+	case string:
+		switch obj := obj.(type) {
+		case *value.String:
+			if obj.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", obj.Value, expected)
+			}
+		case *value.Generator:
+			if obj.Inspect() != expected {
+				t.Errorf("Generator has wrong name. got=%q, want=%q", obj.Inspect(), expected)
+			}
+		default:
+			testErrorObject(t, obj, expected)
+		}
+	default:
+		testNullObject(t, obj)
+	}
+}
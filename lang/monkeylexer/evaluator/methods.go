@@ -0,0 +1,209 @@
+package evaluator
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+// methods holds the methods of each type, a method is a builtin function
+// that takes the value it is called on as its first argument
+var (
+	methods   = map[value.ObjectType]map[string]value.BuiltinFunction{}
+	methodsMu sync.RWMutex
+)
+
+// RegisterMethod makes fn callable as name on the values of type t, as
+// receiver.name(args), fn gets the receiver followed by the arguments.
+// A method registered again under the same name replaces the previous one,
+// it is safe to register methods while programs are evaluated
+func RegisterMethod(t value.ObjectType, name string, fn value.BuiltinFunction) {
+	methodsMu.Lock()
+	defer methodsMu.Unlock()
+	if methods[t] == nil {
+		methods[t] = map[string]value.BuiltinFunction{}
+	}
+	methods[t][name] = fn
+}
+
+// unregisterMethod removes the method name of type t
+func unregisterMethod(t value.ObjectType, name string) {
+	methodsMu.Lock()
+	defer methodsMu.Unlock()
+	delete(methods[t], name)
+}
+
+// lookupMethod returns the method name of the receiver bound to it
+func lookupMethod(receiver value.Object, name string) (*value.Builtin, bool) {
+	methodsMu.RLock()
+	fn, ok := methods[receiver.Type()][name]
+	methodsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return &value.Builtin{Fn: func(args ...value.Object) value.Object {
+		return fn(append([]value.Object{receiver}, args...)...)
+	}}, true
+}
+
+// the methods reuse the builtins where there is one and call back into the
+// evaluator, they are registered in init to break the initialization cycle
+func init() {
+	RegisterMethod(value.STRING_VAL, "len", builtinMethod("len", 0))
+	RegisterMethod(value.STRING_VAL, "upper", stringMethod("upper", strings.ToUpper))
+	RegisterMethod(value.STRING_VAL, "lower", stringMethod("lower", strings.ToLower))
+	RegisterMethod(value.STRING_VAL, "trim", stringMethod("trim", strings.TrimSpace))
+	RegisterMethod(value.STRING_VAL, "split", stringSplit)
+
+	RegisterMethod(value.ARRAY_VAL, "len", arrayLen)
+	RegisterMethod(value.ARRAY_VAL, "last", builtinMethod("last", 0))
+	RegisterMethod(value.ARRAY_VAL, "push", builtinMethod("push", 1))
+	RegisterMethod(value.ARRAY_VAL, "map", arrayMap)
+	RegisterMethod(value.ARRAY_VAL, "filter", arrayFilter)
+	RegisterMethod(value.ARRAY_VAL, "join", arrayJoin)
+
+	RegisterMethod(value.HASH_VAL, "len", hashLen)
+	RegisterMethod(value.HASH_VAL, "keys", hashKeys)
+	RegisterMethod(value.HASH_VAL, "values", hashValues)
+}
+
+// checkMethodArgs checks that the method name got want arguments besides
+// its receiver
+func checkMethodArgs(name string, args []value.Object, want int) *value.Error {
+	return CheckArity(name, want, want, len(args)-1)
+}
+
+// builtinMethod makes a method of the builtin name, which takes the receiver
+// and want arguments
+func builtinMethod(name string, want int) value.BuiltinFunction {
+	fn := builtins[name].Fn
+	return func(args ...value.Object) value.Object {
+		if err := checkMethodArgs(name, args, want); err != nil {
+			return err
+		}
+		return fn(args...)
+	}
+}
+
+// stringMethod makes the method name of a function from string to string
+func stringMethod(name string, fn func(string) string) value.BuiltinFunction {
+	return func(args ...value.Object) value.Object {
+		if err := checkMethodArgs(name, args, 0); err != nil {
+			return err
+		}
+		return &value.String{Value: fn(args[0].(*value.String).Value)}
+	}
+}
+
+// stringSplit splits the receiver around a separator, an empty one splits
+// it into its characters
+func stringSplit(args ...value.Object) value.Object {
+	if err := checkMethodArgs("split", args, 1); err != nil {
+		return err
+	}
+	sep, ok := args[1].(*value.String)
+	if !ok {
+		return newError("argument to `split` must be STRING, got %s", args[1].Type())
+	}
+	parts := strings.Split(args[0].(*value.String).Value, sep.Value)
+	elements := make([]value.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &value.String{Value: part}
+	}
+	return &value.Array{Elements: elements}
+}
+
+func arrayLen(args ...value.Object) value.Object {
+	if err := checkMethodArgs("len", args, 0); err != nil {
+		return err
+	}
+	return &value.Integer{Value: int64(len(args[0].(*value.Array).Elements))}
+}
+
+// arrayMap returns the array of the results of the function on each element
+func arrayMap(args ...value.Object) value.Object {
+	if err := checkMethodArgs("map", args, 1); err != nil {
+		return err
+	}
+	elements := args[0].(*value.Array).Elements
+	mapped := make([]value.Object, len(elements))
+	for i, e := range elements {
+		result := applyFunction(args[1], []value.Object{e})
+		if isError(result) {
+			return result
+		}
+		mapped[i] = result
+	}
+	return &value.Array{Elements: mapped}
+}
+
+// arrayFilter returns the array of the elements the function is truthy for
+func arrayFilter(args ...value.Object) value.Object {
+	if err := checkMethodArgs("filter", args, 1); err != nil {
+		return err
+	}
+	filtered := []value.Object{}
+	for _, e := range args[0].(*value.Array).Elements {
+		result := applyFunction(args[1], []value.Object{e})
+		if isError(result) {
+			return result
+		}
+		if IsTruthy(result) {
+			filtered = append(filtered, e)
+		}
+	}
+	return &value.Array{Elements: filtered}
+}
+
+// arrayJoin joins the elements with a separator, strings as they are and
+// the other values in their Inspect form
+func arrayJoin(args ...value.Object) value.Object {
+	if err := checkMethodArgs("join", args, 1); err != nil {
+		return err
+	}
+	sep, ok := args[1].(*value.String)
+	if !ok {
+		return newError("argument to `join` must be STRING, got %s", args[1].Type())
+	}
+	parts := []string{}
+	for _, e := range args[0].(*value.Array).Elements {
+		if s, ok := e.(*value.String); ok {
+			parts = append(parts, s.Value)
+		} else {
+			parts = append(parts, e.Inspect())
+		}
+	}
+	return &value.String{Value: strings.Join(parts, sep.Value)}
+}
+
+func hashLen(args ...value.Object) value.Object {
+	if err := checkMethodArgs("len", args, 0); err != nil {
+		return err
+	}
+	return &value.Integer{Value: int64(args[0].(*value.Hash).Len())}
+}
+
+// hashKeys returns the keys of the hash in insertion order
+func hashKeys(args ...value.Object) value.Object {
+	if err := checkMethodArgs("keys", args, 0); err != nil {
+		return err
+	}
+	keys := []value.Object{}
+	for _, pair := range args[0].(*value.Hash).Pairs() {
+		keys = append(keys, pair.Key)
+	}
+	return &value.Array{Elements: keys}
+}
+
+// hashValues returns the values of the hash in insertion order
+func hashValues(args ...value.Object) value.Object {
+	if err := checkMethodArgs("values", args, 0); err != nil {
+		return err
+	}
+	values := []value.Object{}
+	for _, pair := range args[0].(*value.Hash).Pairs() {
+		values = append(values, pair.Value)
+	}
+	return &value.Array{Elements: values}
+}
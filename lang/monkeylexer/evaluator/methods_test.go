@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"Monkey".upper()`, "MONKEY"},
+		{`"Monkey".lower()`, "monkey"},
+		{`"  pad ".trim()`, "pad"},
+		{`"café".len()`, 4},
+		{`"a,b,c".split(",")[1]`, "b"},
+		{`[1, 2, 3].len()`, 3},
+		{`[1, 2, 3].last()`, 3},
+		{`[1, 2].push(3).len()`, 3},
+		{`[1, 2, 3].map(fn(x) { x * x })[2]`, 9},
+		{`[1, 2, 3, 4].filter(fn(x) { x > 2 }).join(" ")`, "3 4"},
+		{`["a", 1, true].join(", ")`, "a, 1, true"},
+		{`{"a": 1, "b": 2}.keys().join("")`, "ab"},
+		{`{"a": 1, "b": 2}.values()[1]`, 2},
+		{`{"a": 1}.len()`, 1},
+		{`let upper = "x".upper; upper()`, "X"},
+		// a field comes before the method of the same name
+		{`{"keys": fn() { 7 }}.keys()`, 7},
+		{`"a".upper(1)`, "wrong number of arguments for upper. got=1, want=0"},
+		{`[1].push()`, "wrong number of arguments for push. got=0, want=1"},
+		{`{}.keys(1, 2)`, "wrong number of arguments for keys. got=2, want=0"},
+		{`"a,b".split()`, "wrong number of arguments for split. got=0, want=1"},
+		{`"a".split(1)`, "argument to `split` must be STRING, got INTEGER"},
+		{`[1].map(fn(x) { x + "a" })`, "type mismatch: INTEGER + STRING"},
+		{`1.upper()`, "no method upper on INTEGER"},
+		{`{}.upper()`, "no such field: upper"},
+	}
+	for _, tt := range tests {
+		testMethodResult(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestRegisterMethod(t *testing.T) {
+	RegisterMethod(value.INTEGER_VAL, "double", func(args ...value.Object) value.Object {
+		return &value.Integer{Value: args[0].(*value.Integer).Value * 2}
+	})
+	t.Cleanup(func() { unregisterMethod(value.INTEGER_VAL, "double") })
+
+	testIntegerObject(t, testEval("let x = 21; x.double()"), 42)
+	testErrorObject(t, testEval("true.double()"), "no method double on BOOLEAN")
+}
+
+func TestRegisterMethodWhileEvaluating(t *testing.T) {
+	t.Cleanup(func() { unregisterMethod(value.INTEGER_VAL, "inc") })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			RegisterMethod(value.INTEGER_VAL, "inc", func(args ...value.Object) value.Object {
+				return &value.Integer{Value: args[0].(*value.Integer).Value + 1}
+			})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		testEval(`"a".upper()`)
+	}
+	<-done
+	testIntegerObject(t, testEval("1.inc()"), 2)
+}
+
+func testMethodResult(t *testing.T, obj value.Object, expected interface{}) {
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, obj, int64(expected))
+	case string:
+		if str, ok := obj.(*value.String); ok {
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+			return
+		}
+		testErrorObject(t, obj, expected)
+	}
+}
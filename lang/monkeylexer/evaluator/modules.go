@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+// SourceExt is the extension tried when an imported path has none
+const SourceExt = ".monkey"
+
+// EvalFile reads, parses and evaluates the source file at path in env,
+// imports made by the file are resolved relative to it and cached in the
+// session of env. When the file doesn't parse it is not evaluated and the
+// parser diagnostics are returned instead, their spans point into path
+func EvalFile(path string, env *value.Environment) (value.Object, []diagnostic.Diagnostic) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return newError("could not read %s: %s", path, err), nil
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostics := p.Diagnostics()
+		for i := range diagnostics {
+			diagnostics[i].Span.File = path
+		}
+		return nil, diagnostics
+	}
+	return Eval(program, env.WithFile(path)), nil
+}
+
+// evalImportExpression evaluates an import expression
+// this functions resolves the imported path relative to the importing file
+// and returns the module cached in the session or evaluates the file in its
+// own environment
+func evalImportExpression(node *ast.ImportExpression, env *value.Environment) value.Object {
+	path, err := resolveImport(node.Path, env.File())
+	if err != nil {
+		return newError("import %q: %s", node.Path, err)
+	}
+	session := env.Session()
+	if module, ok := session.Modules[path]; ok {
+		return module
+	}
+	for i, file := range session.Importing {
+		if file == path {
+			cycle := []string{}
+			for _, f := range session.Importing[i:] {
+				cycle = append(cycle, filepath.Base(f))
+			}
+			cycle = append(cycle, filepath.Base(path))
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	session.Importing = append(session.Importing, path)
+	defer func() { session.Importing = session.Importing[:len(session.Importing)-1] }()
+
+	moduleEnv := env.ModuleEnvironment(path)
+	result, diagnostics := EvalFile(path, moduleEnv)
+	if len(diagnostics) != 0 {
+		// the importing file reports a single error, it points at the
+		// first one of the imported file
+		messages := make([]string, len(diagnostics))
+		for i, d := range diagnostics {
+			messages[i] = d.Error()
+		}
+		err := newError("could not parse %s: %s", path, strings.Join(messages, "; "))
+		err.Span = diagnostics[0].Span
+		return err
+	}
+	if isError(result) {
+		return result
+	}
+
+	attrs := &value.Hash{}
+	for _, name := range moduleEnv.Names() {
+		val, _ := moduleEnv.Get(name)
+		attrs.Set(&value.String{Value: name}, val)
+	}
+	module := &value.Module{Name: strings.TrimSuffix(filepath.Base(path), SourceExt), Attrs: attrs}
+	session.Modules[path] = module
+	return module
+}
+
+// resolveImport returns the absolute path of an imported file, relative paths
+// are resolved from the directory of the importing file or the working directory
+func resolveImport(path, from string) (string, error) {
+	if !filepath.IsAbs(path) && from != "" {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if filepath.Ext(path) != "" {
+			return "", err
+		}
+		if _, extErr := os.Stat(path + SourceExt); extErr != nil {
+			return "", err
+		}
+		path += SourceExt
+	}
+	return path, nil
+}
+
+// evalModuleIndexExpression evaluates an index expression on a module
+// this functions returns the top-level binding with the given name
+func evalModuleIndexExpression(module, index value.Object) value.Object {
+	moduleObject := module.(*value.Module)
+	name, ok := index.(*value.String)
+	if !ok {
+		return newError("module index must be STRING, got %s", index.Type())
+	}
+	val, ok := moduleObject.Attrs.Get(name)
+	if !ok {
+		return newError("module %s has no binding %s", moduleObject.Name, name.Value)
+	}
+	return val
+}
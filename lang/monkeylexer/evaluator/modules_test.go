@@ -0,0 +1,149 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportExpressions(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.monkey":       `let math = import "lib/math"; math["double"](math["two"]);`,
+		"lib/math.monkey":   `let two = import "consts"["two"]; let double = fn(x) { x * 2 };`,
+		"lib/consts":        `let two = 2;`,
+		"cached.monkey":     `import "lib/math" == import "lib/math.monkey"`,
+		"missing.monkey":    `import "lib/math"["triple"]`,
+		"member.monkey":     `let math = import "lib/math"; math.double(math.two) + 1;`,
+		"nomember.monkey":   `import "lib/math".triple`,
+		"cycle.monkey":      `import "a"`,
+		"a.monkey":          `let b = import "b";`,
+		"b.monkey":          `let a = import "a";`,
+		"notfound.monkey":   `import "nope"`,
+		"badparse.monkey":   `import "lib/broken"`,
+		"lib/broken.monkey": `let = 1;`,
+	})
+
+	tests := []struct {
+		file     string
+		expected interface{}
+	}{
+		{"main.monkey", 4},
+		{"cached.monkey", true},
+		{"missing.monkey", "module math has no binding triple"},
+		{"member.monkey", 5},
+		{"nomember.monkey", "module math has no binding triple"},
+		{"cycle.monkey", "import cycle: a.monkey -> b.monkey -> a.monkey"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		evaluated, _ := EvalFile(path, value.NewModuleEnvironment(path))
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+
+	for _, file := range []string{"notfound.monkey", "badparse.monkey"} {
+		path := filepath.Join(dir, file)
+		evaluated, _ := EvalFile(path, value.NewModuleEnvironment(path))
+		if _, ok := evaluated.(*value.Error); !ok {
+			t.Errorf("%s: object is not Error. got=%T (%+v)", file, evaluated, evaluated)
+		}
+	}
+}
+
+func TestEvalFileDiagnostics(t *testing.T) {
+	dir := writeModules(t, map[string]string{"broken.monkey": "let a = ;\nlet b = \"\\q\";"})
+	path := filepath.Join(dir, "broken.monkey")
+
+	evaluated, diagnostics := EvalFile(path, value.NewModuleEnvironment(path))
+	if evaluated != nil {
+		t.Errorf("file with errors evaluated to %s", evaluated.Inspect())
+	}
+	expected := []struct {
+		code    diagnostic.Code
+		message string
+		line    int
+		column  int
+	}{
+		{diagnostic.Syntax, "no prefix parse function for ; found", 1, 9},
+		{diagnostic.Lexical, "invalid escape sequence \\q", 2, 10},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("wrong number of diagnostics. expected=%d, got=%d (%v)", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, d := range diagnostics {
+		want := expected[i]
+		if d.Code != want.code || d.Message != want.message {
+			t.Errorf("diagnostics[%d] wrong. expected=%s %q, got=%s %q", i, want.code, want.message, d.Code, d.Message)
+		}
+		if d.Span.File != path || d.Span.Start.Line != want.line || d.Span.Start.Column != want.column {
+			t.Errorf("diagnostics[%d] at wrong span. expected=%s:%d:%d, got=%s", i, path, want.line, want.column, d.Span)
+		}
+	}
+}
+
+func TestModulesCachedPerSession(t *testing.T) {
+	dir := writeModules(t, map[string]string{"consts.monkey": `let two = 2;`})
+	path := filepath.Join(dir, "consts.monkey")
+	input := `import "` + path + `".two`
+
+	first := value.NewEnvironment()
+	testIntegerObject(t, testEvalIn(input, first), 2)
+	if err := os.WriteFile(path, []byte(`let two = 3;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session that imported the module keeps it, a new one reads the file again
+	testIntegerObject(t, testEvalIn(input, first), 2)
+	testIntegerObject(t, testEvalIn(input, value.NewEnvironment()), 3)
+}
+
+func TestEvalFileInEnvironment(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/main.monkey":   `let two = import "consts".two;`,
+		"lib/consts.monkey": `let two = 2;`,
+	})
+
+	// the imports of the file are resolved relative to it while its
+	// bindings are made in the environment it is evaluated in
+	env := value.NewEnvironment()
+	if result, _ := EvalFile(filepath.Join(dir, "lib/main.monkey"), env); isError(result) {
+		t.Fatalf("EvalFile returned error: %s", result.Inspect())
+	}
+	two, ok := env.Get("two")
+	if !ok {
+		t.Fatalf("binding two not found in the environment")
+	}
+	testIntegerObject(t, two, 2)
+	if env.File() != "" {
+		t.Errorf("environment file changed to %q", env.File())
+	}
+}
+
+func testEvalIn(input string, env *value.Environment) value.Object {
+	return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+}
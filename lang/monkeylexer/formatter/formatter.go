@@ -0,0 +1,60 @@
+// Package formatter reprints Monkey source in its canonical form: one
+// statement per line, blocks indented, operators spaced and parentheses
+// only where they are needed. Comments are kept next to the code they
+// follow or precede, and formatting formatted source changes nothing.
+package formatter
+
+import (
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
+
+// indent is the indentation of one block level
+const indent = "    "
+
+// SyntaxError is returned for source that does not parse
+type SyntaxError struct {
+	Diagnostics []diagnostic.Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Source returns the formatted form of src
+func Source(src string) (string, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return "", &SyntaxError{Diagnostics: p.Diagnostics()}
+	}
+
+	// the tokens and comments are read again to know where the
+	// statements end and where the comments were
+	l := lexer.New(src)
+	pr := &printer{index: map[position]int{}, first: true}
+	for {
+		tok := l.NextToken()
+		pr.index[position{tok.Line, tok.Column}] = len(pr.tokens)
+		pr.tokens = append(pr.tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	pr.comments = l.Comments()
+
+	pr.statements(program.Statements, pr.tokens[len(pr.tokens)-1])
+	if pr.out.Len() == 0 {
+		return "", nil
+	}
+	pr.out.WriteString("\n")
+	return pr.out.String(), nil
+}
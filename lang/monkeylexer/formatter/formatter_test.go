@@ -0,0 +1,153 @@
+package formatter
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   x=1", "let x = 1;\n"},
+		{"let x = (1 + 2) * 3 - (4 - 5)", "let x = (1 + 2) * 3 - (4 - 5);\n"},
+		{"((a * b)) + c", "a * b + c;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"-(a + b); -f(x)[0]; (-a)[0]; !-a", "-(a + b);\n-f(x)[0];\n(-a)[0];\n!-a;\n"},
+		{"fn(x){x}(5)", "fn(x) {\n    x;\n}(5);\n"},
+		{`let h = {"a":1,true:[1,2]}`, "let h = {\"a\": 1, true: [1, 2]};\n"},
+		{`puts("tab\there", 0xFF, 1_000)`, "puts(\"tab\\there\", 0xFF, 1_000);\n"},
+		{"`hi ${ name }`", "`hi ${ name }`;\n"},
+		{`let m = import "lib"`, "let m = import \"lib\";\n"},
+		{"(a||b)&&c; a||(b&&c)", "(a || b) && c;\na || b && c;\n"},
+		{"a . b(c).d; (-a).b", "a.b(c).d;\n(-a).b;\n"},
+		{"x|>f(1)|>g; (a |> f) + 1", "x |> f(1) |> g;\n(a |> f) + 1;\n"},
+		{"a?b:c?d:e; (a ? b : c) ? d : e; (a ? b : c) + 1", "a ? b : c ? d : e;\n(a ? b : c) ? d : e;\n(a ? b : c) + 1;\n"},
+		{"{a ? 1 : 2: x||y ? f(x) : [y]}", "{a ? 1 : 2: x || y ? f(x) : [y]};\n"},
+		{
+			"let g = fn(xs) { for x range xs { yield x*2 } }",
+			"let g = fn(xs) {\n    for x range xs {\n        yield x * 2;\n    }\n};\n",
+		},
+		{"fn() {}", "fn() {};\n"},
+		{"fn(a,b=1+2,...rest){}", "fn(a, b = 1 + 2, ...rest) {};\n"},
+		{
+			"if (a > b) { return a; } else { b }",
+			"if (a > b) {\n    return a;\n} else {\n    b;\n}\n",
+		},
+		{
+			"let f = fn(x) { let y = fn() { x }; y() }",
+			"let f = fn(x) {\n    let y = fn() {\n        x;\n    };\n    y();\n};\n",
+		},
+		{"", ""},
+	}
+
+	for i, tt := range tests {
+		out, err := Source(tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d] - Source error: %s", i, err)
+		}
+		if out != tt.expected {
+			t.Errorf("tests[%d] - wrong output. expected=%q, got=%q", i, tt.expected, out)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"// header\n\nlet x = 1; // one\nlet y = 2;",
+			"// header\n\nlet x = 1; // one\nlet y = 2;\n",
+		},
+		{
+			"let f = fn() {\n\n  // first\n  1\n\n\n  // last\n}",
+			"let f = fn() {\n    // first\n    1;\n\n    // last\n};\n",
+		},
+		{
+			"let e = fn() { /* empty */ };",
+			"let e = fn() { /* empty */\n};\n",
+		},
+		{
+			"/* a\n   b */\nx\n// end",
+			"/* a\n   b */\nx;\n// end\n",
+		},
+		// comments inside an expression stay where they are
+		{
+			"let s = 1 + /* two */ 2;\nlet t = 3;",
+			"let s = 1 + /* two */ 2;\nlet t = 3;\n",
+		},
+		{
+			"let h = {\n  \"a\": 1,\n  /* b */ \"b\": 2\n};",
+			"let h = {\"a\": 1, /* b */ \"b\": 2};\n",
+		},
+		{
+			"let xs = [\n  1, // one\n  2\n];\nxs",
+			"let xs = [1, // one\n    2];\nxs;\n",
+		},
+		{
+			"f(a /* last */);\ng((/* sum */ a + b) * c)",
+			"f(a /* last */);\ng(/* sum */ (a + b) * c);\n",
+		},
+	}
+
+	for i, tt := range tests {
+		out, err := Source(tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d] - Source error: %s", i, err)
+		}
+		if out != tt.expected {
+			t.Errorf("tests[%d] - wrong output. expected=%q, got=%q", i, tt.expected, out)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	input := `// header comment
+
+let   add=fn(a,b){a+b};   // trailing
+let y = -(a + b) ;
+
+
+/* block
+   comment */
+let f = fn(x) {
+
+  // inside
+  if (x > 0) { return x; } else { x * 2 }
+  let h = {"a": 1, "b": [1,2,3]};
+  h["a"] // the value
+  // last inside
+};
+puts(` + "`multi\n${ x }\nline`" + `, "a\tb");
+let empty = fn() {};
+let xs = [1, // one
+  2, /* two */ 3 /* three */];
+if (true) {1}
+// end
+`
+
+	once, err := Source(input)
+	if err != nil {
+		t.Fatalf("Source error: %s", err)
+	}
+	twice, err := Source(once)
+	if err != nil {
+		t.Fatalf("Source error on formatted source: %s", err)
+	}
+	if once != twice {
+		t.Errorf("formatting is not idempotent.\nonce:\n%s\ntwice:\n%s", once, twice)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Source("let = 1;")
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("err is not *SyntaxError. got=%T (%v)", err, err)
+	}
+	if len(syntaxErr.Diagnostics) != 1 {
+		t.Errorf("wrong number of diagnostics. got=%d", len(syntaxErr.Diagnostics))
+	}
+}
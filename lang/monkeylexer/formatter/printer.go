@@ -0,0 +1,395 @@
+package formatter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
+
+type position struct {
+	line   int
+	column int
+}
+
+// printer writes the formatted program to out
+type printer struct {
+	out   strings.Builder
+	depth int
+
+	// tokens are the tokens of the source, index finds one by its position
+	tokens []token.Token
+	index  map[position]int
+	// comments are the comments not printed yet, in source order
+	comments []token.Token
+	// lastLine is the source line the printed code or comment ends on
+	lastLine int
+	// first is set until the first statement or comment of a block is printed
+	first bool
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+// line starts a new line at the current indentation,
+// after a blank line when blank is set
+func (p *printer) line(blank bool) {
+	if p.out.Len() != 0 {
+		p.write("\n")
+		if blank {
+			p.write("\n")
+		}
+	}
+	p.write(strings.Repeat(indent, p.depth))
+}
+
+// statements prints stmts one per line, end is the token after them
+// where the comments that follow the last statement stop
+func (p *printer) statements(stmts []ast.Statement, end token.Token) {
+	for _, stmt := range stmts {
+		start := startToken(stmt)
+		p.flushComments(start)
+		p.line(p.blankBefore(start))
+		p.first = false
+		p.statement(stmt)
+	}
+	p.flushComments(end)
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.write("let " + stmt.Name.Value + " = ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(stmt.ReturnValue)
+		p.write(";")
+	case *ast.YieldStatement:
+		p.write("yield ")
+		p.expression(stmt.Value)
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		switch stmt.Expression.(type) {
+		case *ast.IfExpression, *ast.ForExpression:
+		default:
+			p.write(";")
+		}
+	}
+}
+
+// block prints a block with its statements indented, an empty block is {}
+func (p *printer) block(block *ast.BlockStatement) {
+	end := p.closing(block.Token)
+	p.write("{")
+	if len(block.Statements) == 0 && (len(p.comments) == 0 || !before(p.comments[0], end)) {
+		p.write("}")
+		return
+	}
+
+	p.depth++
+	p.first = true
+	p.statements(block.Statements, end)
+	p.depth--
+	p.line(false)
+	p.write("}")
+}
+
+// startToken returns the first token of a statement
+func startToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.YieldStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
+
+// closers pairs each opening token with the token that closes it
+var closers = map[token.TokenType]token.TokenType{
+	token.LBRACE:   token.RBRACE,
+	token.LBRACKET: token.RBRACKET,
+	token.LPAREN:   token.RPAREN,
+}
+
+// closing returns the }, ] or ) that closes the {, [ or ( token open
+func (p *printer) closing(open token.Token) token.Token {
+	depth := 0
+	for _, tok := range p.tokens[p.index[position{open.Line, open.Column}]:] {
+		switch tok.Type {
+		case open.Type:
+			depth++
+		case closers[open.Type]:
+			depth--
+			if depth == 0 {
+				return tok
+			}
+		}
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+// flushComments prints the comments before tok, a comment on the line the
+// printed code ends on stays at the end of that line, the others get their own
+func (p *printer) flushComments(tok token.Token) {
+	for len(p.comments) != 0 && before(p.comments[0], tok) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.lastLine = max(p.lastLine, p.endOfTokenBefore(comment))
+		if comment.Line == p.lastLine && p.out.Len() != 0 {
+			p.write(" ")
+		} else {
+			p.line(comment.Line > p.lastLine+1 && !p.first)
+		}
+		p.write(comment.Literal)
+		p.first = false
+		p.lastLine = comment.Line + strings.Count(comment.Literal, "\n")
+	}
+}
+
+// inlineComments prints the comments before tok inside a statement where
+// they are, before the operand starting at tok or, when closes is set, before
+// the bracket tok. A line comment ends the line and the code goes on on the
+// next one, one level deeper
+func (p *printer) inlineComments(tok token.Token, closes bool) {
+	for len(p.comments) != 0 && before(p.comments[0], tok) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if closes {
+			p.write(" ")
+		}
+		p.write(comment.Literal)
+		if strings.HasPrefix(comment.Literal, "//") {
+			p.depth++
+			p.line(false)
+			p.depth--
+		} else if !closes {
+			p.write(" ")
+		}
+		p.lastLine = comment.Line + strings.Count(comment.Literal, "\n")
+	}
+}
+
+// firstToken returns the token an expression starts with
+func firstToken(exp ast.Expression) token.Token {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return firstToken(exp.Left)
+	case *ast.ConditionalExpression:
+		return firstToken(exp.Condition)
+	case *ast.CallExpression:
+		return firstToken(exp.Function)
+	case *ast.IndexExpression:
+		return firstToken(exp.Left)
+	case *ast.MemberExpression:
+		return firstToken(exp.Object)
+	case *ast.Identifier:
+		return exp.Token
+	case *ast.IntegerLiteral:
+		return exp.Token
+	case *ast.Boolean:
+		return exp.Token
+	case *ast.StringLiteral:
+		return exp.Token
+	case *ast.TemplateLiteral:
+		return exp.Token
+	case *ast.ImportExpression:
+		return exp.Token
+	case *ast.PrefixExpression:
+		return exp.Token
+	case *ast.ArrayLiteral:
+		return exp.Token
+	case *ast.HashLiteral:
+		return exp.Token
+	case *ast.FunctionLiteral:
+		return exp.Token
+	case *ast.ForExpression:
+		return exp.Token
+	case *ast.IfExpression:
+		return exp.Token
+	}
+	return token.Token{}
+}
+
+// blankBefore checks if the source has a blank line before tok, the blank
+// lines at the start of a block are dropped
+func (p *printer) blankBefore(tok token.Token) bool {
+	p.lastLine = max(p.lastLine, p.endOfTokenBefore(tok))
+	return tok.Line > p.lastLine+1 && !p.first
+}
+
+// endOfTokenBefore returns the line the last token before tok ends on,
+// 0 when tok is at the start of the source
+func (p *printer) endOfTokenBefore(tok token.Token) int {
+	i := sort.Search(len(p.tokens), func(i int) bool { return !before(p.tokens[i], tok) })
+	if i == 0 {
+		return 0
+	}
+	t := p.tokens[i-1]
+	if t.Type == token.TEMPLATE {
+		return t.Line + strings.Count(t.Literal, "\n")
+	}
+	return t.Line
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+func (p *printer) expression(exp ast.Expression) {
+	p.inlineComments(firstToken(exp), false)
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+	case *ast.IntegerLiteral:
+		// the literal keeps its base and digit separators
+		p.write(exp.Token.Literal)
+	case *ast.Boolean:
+		p.write(exp.Token.Literal)
+	case *ast.StringLiteral:
+		p.write(strconv.Quote(exp.Value))
+	case *ast.TemplateLiteral:
+		// the text of a template is printed as written
+		p.write("`" + exp.Token.Literal + "`")
+	case *ast.ImportExpression:
+		p.write("import " + strconv.Quote(exp.Path))
+	case *ast.PrefixExpression:
+		p.write(exp.Operator)
+		p.operand(exp.Right, parser.PREFIX, false)
+	case *ast.InfixExpression:
+		precedence := parser.Precedence(exp.Token.Type)
+		p.operand(exp.Left, precedence, false)
+		p.write(" " + exp.Operator + " ")
+		p.operand(exp.Right, precedence, true)
+	case *ast.ConditionalExpression:
+		// conditionals nest to the right, in the condition they need parentheses
+		p.operand(exp.Condition, parser.CONDITIONAL, true)
+		p.write(" ? ")
+		p.expression(exp.Consequence)
+		p.write(" : ")
+		p.expression(exp.Alternative)
+	case *ast.CallExpression:
+		p.operand(exp.Function, parser.CALL, false)
+		p.write("(")
+		p.expressions(exp.Arguments)
+		p.inlineComments(p.closing(exp.Token), true)
+		p.write(")")
+	case *ast.IndexExpression:
+		// calls, indexes and members chain from left to right, as f(x)[0]
+		p.operand(exp.Left, parser.CALL, false)
+		p.write("[")
+		p.expression(exp.Index)
+		p.inlineComments(p.closing(exp.Token), true)
+		p.write("]")
+	case *ast.MemberExpression:
+		p.operand(exp.Object, parser.CALL, false)
+		p.write("." + exp.Property.Value)
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.expressions(exp.Elements)
+		p.inlineComments(p.closing(exp.Token), true)
+		p.write("]")
+	case *ast.HashLiteral:
+		p.write("{")
+		for i, key := range exp.Keys {
+			if i != 0 {
+				p.write(", ")
+			}
+			p.expression(key)
+			p.write(": ")
+			p.expression(exp.Pairs[key])
+		}
+		p.inlineComments(p.closing(exp.Token), true)
+		p.write("}")
+	case *ast.FunctionLiteral:
+		p.write("fn(")
+		for i, param := range exp.Parameters {
+			if i != 0 {
+				p.write(", ")
+			}
+			p.write(param.Value)
+			if i < len(exp.Defaults) && exp.Defaults[i] != nil {
+				p.write(" = ")
+				p.expression(exp.Defaults[i])
+			}
+		}
+		if exp.Rest != nil {
+			if len(exp.Parameters) != 0 {
+				p.write(", ")
+			}
+			p.write("..." + exp.Rest.Value)
+		}
+		p.write(") ")
+		p.block(exp.Body)
+	case *ast.ForExpression:
+		p.write("for " + exp.Variable.Value + " range ")
+		p.expression(exp.Iterable)
+		p.write(" ")
+		p.block(exp.Body)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(exp.Condition)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.write(" else ")
+			p.block(exp.Alternative)
+		}
+	}
+}
+
+func (p *printer) expressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i != 0 {
+			p.write(", ")
+		}
+		p.expression(exp)
+	}
+}
+
+// operand prints exp as an operand of an operator of the given precedence,
+// in parentheses when it binds looser, or as tight on the right side since
+// the operators are left associative
+func (p *printer) operand(exp ast.Expression, precedence int, right bool) {
+	own := precedenceOf(exp)
+	if own < precedence || own == precedence && right {
+		// a comment before the operand goes before its parenthesis
+		p.inlineComments(firstToken(exp), false)
+		p.write("(")
+		p.expression(exp)
+		p.write(")")
+		return
+	}
+	p.expression(exp)
+}
+
+// precedenceOf returns the precedence of the operator of exp,
+// expressions without one bind tighter than any operator
+func precedenceOf(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.ConditionalExpression:
+		return parser.CONDITIONAL
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return parser.INDEX
+	}
+	return parser.INDEX + 1
+}
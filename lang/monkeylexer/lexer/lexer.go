@@ -1,46 +1,161 @@
 package lexer
 
-import "github.com/delavalom/arvlang/lang/monkeylexer/token"
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+)
 
 type Lexer struct {
 	input        string
 	position     int  // current position in input (points to current char) readPosition int // current reading position in input (after current char) ch byte // current char under examination
 	readPosition int  // current reading position in input (after current char) ch byte // current char under examination
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char, counted in runes
+	errors       []diagnostic.Diagnostic
+	comments     []token.Token
+	// incomplete is set when the input ends inside a string, a template
+	// or a block comment
+	incomplete bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Errors returns the errors found while reading the tokens so far
+func (l *Lexer) Errors() []string {
+	errors := make([]string, len(l.errors))
+	for i, err := range l.errors {
+		errors[i] = err.Error()
+	}
+	return errors
+}
+
+// Diagnostics returns the errors found while reading the tokens so far
+func (l *Lexer) Diagnostics() []diagnostic.Diagnostic {
+	return l.errors
+}
+
+// registerError registers an error found at the given line and column
+func (l *Lexer) registerError(line, column int, format string, a ...interface{}) {
+	l.report(diagnostic.Errorf(diagnostic.Point(line, column), diagnostic.Lexical, format, a...))
+}
+
+// Incomplete checks if the input ended inside a string, a template or
+// a block comment, which more input could close
+func (l *Lexer) Incomplete() bool {
+	return l.incomplete
+}
+
+func (l *Lexer) report(d diagnostic.Diagnostic) {
+	l.errors = append(l.errors, d)
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
+		if l.ch == utf8.RuneError && width == 1 {
+			l.registerError(l.line, l.column, "invalid UTF-8 encoding")
+		}
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += max(width, 1)
 }
 
+// skipWhitespace skips the whitespace and the comments before the next token
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			l.readLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			l.readBlockComment()
+		default:
+			return
+		}
+	}
+}
+
+// Comments returns the comments skipped so far, in source order,
+// so tools such as formatters can put them back
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// readLineComment reads a // comment up to the end of the line
+func (l *Lexer) readLineComment() {
+	position, line, column := l.position, l.line, l.column
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	l.addComment(l.input[position:l.position], line, column)
 }
 
+// readBlockComment reads a /* */ comment, block comments nest
+// so commenting out code that has comments keeps working
+func (l *Lexer) readBlockComment() {
+	position, line, column := l.position, l.line, l.column
+	depth := 0
+	for {
+		switch {
+		case l.ch == 0:
+			l.incomplete = true
+			l.report(diagnostic.Errorf(diagnostic.Point(line, column), diagnostic.Lexical, "unterminated block comment").
+				WithNote("block comments nest, every /* needs its own */"))
+			l.addComment(l.input[position:l.position], line, column)
+			return
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				l.addComment(l.input[position:l.position], line, column)
+				return
+			}
+		}
+		l.readChar()
+	}
+}
+
+func (l *Lexer) addComment(text string, line, column int) {
+	l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: text, Line: line, Column: column})
+}
+
+// readIdentifier reads a letter or '_' followed by letters, digits or '_'
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isLetter checks if the given rune can start an identifier,
+// any Unicode letter or '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
 var keywords = map[string]token.TokenType{
@@ -51,6 +166,20 @@ var keywords = map[string]token.TokenType{
 	"if":     token.IF,
 	"else":   token.ELSE,
 	"return": token.RETURN,
+	"import": token.IMPORT,
+	"for":    token.FOR,
+	"range":  token.RANGE,
+	"yield":  token.YIELD,
+}
+
+// Keywords returns the sorted keywords of the language
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func LookupIdent(ident string) token.TokenType {
@@ -60,41 +189,193 @@ func LookupIdent(ident string) token.TokenType {
 	return token.IDENT
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
+// numberBases maps the prefix letter of an integer literal to its base name
+// and the digits it accepts
+var numberBases = map[rune]struct {
+	name  string
+	digit func(rune) bool
+}{
+	'x': {"hexadecimal", isHexDigit},
+	'o': {"octal", func(ch rune) bool { return '0' <= ch && ch <= '7' }},
+	'b': {"binary", func(ch rune) bool { return ch == '0' || ch == '1' }},
+}
+
+// readNumber reads an integer literal, decimal or prefixed by 0x, 0o or 0b,
+// whose digits can be separated by single underscores, a decimal literal
+// can't start with 0 unless it is 0,
+// it reports false when the literal is malformed
+func (l *Lexer) readNumber() (string, bool) {
+	position, line, column := l.position, l.line, l.column
+	name, digit := "decimal", isDigit
+	if base, ok := numberBases[unicode.ToLower(l.peekChar())]; ok && l.ch == '0' {
+		name, digit = base.name, base.digit
+		l.readChar()
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	digits := l.position
+	for isHexDigit(l.ch) && (name == "hexadecimal" || isDigit(l.ch)) || l.ch == '_' {
+		l.readChar()
+	}
+	literal := l.input[position:l.position]
+
+	switch body := l.input[digits:l.position]; {
+	case strings.Trim(body, "_") == "":
+		l.registerError(line, column, "%s literal %s has no digits", name, literal)
+		return literal, false
+	case strings.Contains(body, "__") || strings.HasSuffix(body, "_"):
+		l.registerError(line, column, "'_' must separate successive digits in %s", literal)
+		return literal, false
+	case name == "decimal" && len(body) > 1 && body[0] == '0':
+		l.registerError(line, column, "decimal literal %s has a leading zero, octal literals start with 0o", literal)
+		return literal, false
+	default:
+		for _, ch := range body {
+			if ch != '_' && !digit(ch) {
+				l.registerError(line, column, "invalid digit %q in %s literal %s", ch, name, literal)
+				return literal, false
+			}
+		}
+	}
+	return literal, true
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// readString reads a string literal decoding its escape sequences,
+// it reports false when the string is unterminated or has invalid escapes
+func (l *Lexer) readString() (string, bool) {
+	var out strings.Builder
+	line, column := l.line, l.column
+	ok := true
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case 0:
+			l.incomplete = true
+			l.registerError(line, column, "unterminated string")
+			return out.String(), false
+		case '"':
+			return out.String(), ok
+		case '\\':
+			r, multibyte, tail, err := strconv.UnquoteChar(l.input[l.position:], '"')
+			if err != nil {
+				l.registerError(l.line, l.column, "invalid escape sequence %s", l.input[l.position:min(l.position+2, len(l.input))])
+				ok = false
+				l.readChar()
+				continue
+			}
+			if r < utf8.RuneSelf || !multibyte {
+				out.WriteByte(byte(r))
+			} else {
+				out.WriteRune(r)
+			}
+			for end := len(l.input) - len(tail); l.readPosition < end; {
+				l.readChar()
+			}
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
-	return l.input[position:l.position]
 }
 
-func (l *Lexer) peekChar() byte {
+// readTemplate reads the raw text of a template literal up to the closing
+// backtick, backticks inside ${} interpolations do not close the template,
+// it reports false when the template is unterminated
+func (l *Lexer) readTemplate() (string, bool) {
+	position := l.position + 1
+	line, column := l.line, l.column
+	end := templateEnd(l.input, position)
+	for l.ch != 0 && (end < 0 || l.position < end) {
+		l.readChar()
+	}
+	if end < 0 {
+		l.incomplete = true
+		l.registerError(line, column, "unterminated template")
+		return l.input[position:l.position], false
+	}
+	return l.input[position:end], true
+}
+
+// templateEnd returns the index in src of the backtick closing the template
+// whose text starts at start, or -1 when there is none
+func templateEnd(src string, start int) int {
+	for i := start; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`':
+			return i
+		case src[i] == '$' && i+1 < len(src) && src[i+1] == '{':
+			end := InterpolationEnd(src, i+2)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		}
+	}
+	return -1
+}
+
+// InterpolationEnd returns the index in src of the } closing the ${}
+// interpolation whose expression starts at start, or -1 when there is none.
+// The braces and backticks of the strings and nested templates of the
+// expression are skipped, so the lexer and the parser agree on where an
+// interpolation ends
+func InterpolationEnd(src string, start int) int {
+	depth := 1
+	for i := start; i < len(src); i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"':
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			end := templateEnd(src, i+1)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		}
+	}
+	return -1
+}
+
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return r
 	}
 }
 
 func (l *Lexer) NextToken() token.Token {
-
 	l.skipWhitespace()
 
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+// readToken reads the token starting at the current char
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 	switch l.ch {
 	case '=':
@@ -144,9 +425,49 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.GT, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: token.AND}
+		} else {
+			l.registerError(l.line, l.column, "illegal character %q", l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: token.OR}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.PIPE, Literal: token.PIPE}
+		} else {
+			l.registerError(l.line, l.column, "illegal character %q", l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], token.ELLIPSIS) {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: token.ELLIPSIS}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case '"':
+		literal, ok := l.readString()
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		if !ok {
+			tok.Type = token.ILLEGAL
+		}
+		tok.Literal = literal
+	case '`':
+		literal, ok := l.readTemplate()
+		tok.Type = token.TEMPLATE
+		if !ok {
+			tok.Type = token.ILLEGAL
+		}
+		tok.Literal = literal
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -156,16 +477,24 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
+			literal, ok := l.readNumber()
 			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			if !ok {
+				tok.Type = token.ILLEGAL
+			}
+			tok.Literal = literal
 			return tok
+		} else if l.ch == utf8.RuneError && l.readPosition-l.position == 1 {
+			// the invalid encoding was registered by readChar
+			tok = newToken(token.ILLEGAL, l.ch)
 		} else {
+			l.registerError(l.line, l.column, "illegal character %q", l.ch)
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 	l.readChar()
 	return tok
 }
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
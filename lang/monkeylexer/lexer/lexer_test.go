@@ -13,7 +13,7 @@ let ten = 10;
      x + y;
 };
    let result = add(five, ten);
-   !-/*5;
+   !-/ *5;
    5 < 10 > 5;
    if (5 < 10) {
        return true;
@@ -26,6 +26,11 @@ let ten = 10;
 "foo bar"
 [1, 2];
 {"foo": "bar"}
+a && b || c;
+a.b;
+a |> b;
+a ? b : c;
+for x range y { yield x; }
 `
 	tests := []struct {
 		expectedType    token.TokenType
@@ -117,6 +122,35 @@ let ten = 10;
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.DOT, "."},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.FOR, "for"},
+		{token.IDENT, "x"},
+		{token.RANGE, "range"},
+		{token.IDENT, "y"},
+		{token.LBRACE, "{"},
+		{token.YIELD, "yield"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -132,3 +166,307 @@ let ten = 10;
 		}
 	}
 }
+
+func TestTemplateToken(t *testing.T) {
+	input := "`hello ${name}\n${ {\"a\": `}`}[\"a\"] } \\` done`;`a ${ `{` } b`;`${ `x ${ `}` }` }`;"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TEMPLATE, "hello ${name}\n${ {\"a\": `}`}[\"a\"] } \\` done"},
+		{token.SEMICOLON, ";"},
+		{token.TEMPLATE, "a ${ `{` } b"},
+		{token.SEMICOLON, ";"},
+		{token.TEMPLATE, "${ `x ${ `}` }` }"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"tab\there"`, "tab\there"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\r\a\b\f\v\000"`, "\r\a\b\f\v\x00"},
+		{`"\x41é\U0001F600"`, "Aé\U0001F600"},
+		{`"caf\303\251"`, "café"},
+		{`"café"`, "café"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Fatalf("%s - tokentype wrong. expected=%q, got=%q (%v)", tt.input, token.STRING, tok.Type, l.Errors())
+		}
+		if tok.Literal != tt.expected {
+			t.Errorf("%s - literal wrong. expected=%q, got=%q", tt.input, tt.expected, tok.Literal)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let s = "open`, []string{"unterminated string at 1:9"}},
+		{"\n  \"a\\qb\";", []string{`invalid escape sequence \q at 2:5`}},
+		{`"\x4"`, []string{`invalid escape sequence \x at 1:2`}},
+		{"1 @ 2", []string{"illegal character '@' at 1:3"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		illegal := false
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			illegal = illegal || tok.Type == token.ILLEGAL
+		}
+		if !illegal {
+			t.Errorf("%q - no ILLEGAL token", tt.input)
+		}
+		if len(l.Errors()) != len(tt.expected) {
+			t.Fatalf("%q - wrong number of errors. expected=%v, got=%v", tt.input, tt.expected, l.Errors())
+		}
+		for i, msg := range tt.expected {
+			if l.Errors()[i] != msg {
+				t.Errorf("%q - wrong error. expected=%q, got=%q", tt.input, msg, l.Errors()[i])
+			}
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"a\";"
+	expected := [][2]int{{1, 1}, {1, 5}, {1, 7}, {1, 9}, {1, 10}, {2, 3}, {2, 5}, {2, 7}, {2, 10}, {2, 11}}
+
+	l := New(input)
+	for i, pos := range expected {
+		tok := l.NextToken()
+		if tok.Line != pos[0] || tok.Column != pos[1] {
+			t.Errorf("tokens[%d] %q - position wrong. expected=%d:%d, got=%d:%d",
+				i, tok.Literal, pos[0], pos[1], tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := "let café = \"😀 ü\"; naïve + 日本;"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		column          int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "café", 5},
+		{token.ASSIGN, "=", 10},
+		{token.STRING, "😀 ü", 12},
+		{token.SEMICOLON, ";", 17},
+		{token.IDENT, "naïve", 19},
+		{token.PLUS, "+", 25},
+		{token.IDENT, "日本", 27},
+		{token.SEMICOLON, ";", 29},
+		{token.EOF, "", 30},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Column != tt.column {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.column, tok.Column)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = \xff;", "invalid UTF-8 encoding at 1:9"},
+		{"\"ok\"\n\"a\xc3\"", "invalid UTF-8 encoding at 2:3"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0] != tt.expected {
+			t.Errorf("%q - wrong errors. expected=%q, got=%v", tt.input, tt.expected, l.Errors())
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing
+/* block /* nested */ still comment */ x / 2;
+/**/x`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	expected := []token.Token{
+		{Type: token.COMMENT, Literal: "// leading comment", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Column: 12},
+		{Type: token.COMMENT, Literal: "/* block /* nested */ still comment */", Line: 3, Column: 1},
+		{Type: token.COMMENT, Literal: "/**/", Line: 4, Column: 1},
+	}
+	if len(l.Comments()) != len(expected) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expected), len(l.Comments()))
+	}
+	for i, comment := range expected {
+		if l.Comments()[i] != comment {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, comment, l.Comments()[i])
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("x;\n  /* open /* nested */ still open")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	expected := "unterminated block comment at 2:3"
+	if len(l.Errors()) != 1 || l.Errors()[0] != expected {
+		t.Fatalf("wrong errors. expected=%q, got=%v", expected, l.Errors())
+	}
+	if notes := l.Diagnostics()[0].Notes; len(notes) != 1 {
+		t.Errorf("expected a note on nesting. got=%v", notes)
+	}
+}
+
+func TestIdentifiersWithDigits(t *testing.T) {
+	input := `let x1 = user2Id + html5; _9 1a`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x1"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "user2Id"},
+		{token.PLUS, "+"},
+		{token.IDENT, "html5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "_9"},
+		{token.INT, "1"},
+		{token.IDENT, "a"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIntegerLiterals(t *testing.T) {
+	input := `0xFF 0Xdead_BEEF 0o17 0O7 0b1010_0101 0B1 1_000_000 0x_1 42 0 0x0`
+	expected := []string{"0xFF", "0Xdead_BEEF", "0o17", "0O7", "0b1010_0101", "0B1", "1_000_000", "0x_1", "42", "0", "0x0"}
+
+	l := New(input)
+	for i, literal := range expected {
+		tok := l.NextToken()
+		if tok.Type != token.INT {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q (%v)", i, token.INT, tok.Type, l.Errors())
+		}
+		if tok.Literal != literal {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, literal, tok.Literal)
+		}
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF, got=%q", tok.Type)
+	}
+}
+
+func TestMalformedIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0x", "hexadecimal literal 0x has no digits at 1:1"},
+		{"0b_;", "binary literal 0b_ has no digits at 1:1"},
+		{"1__0", "'_' must separate successive digits in 1__0 at 1:1"},
+		{"x = 100_;", "'_' must separate successive digits in 100_ at 1:5"},
+		{"0b102", "invalid digit '2' in binary literal 0b102 at 1:1"},
+		{"0o78", "invalid digit '8' in octal literal 0o78 at 1:1"},
+		{"010", "decimal literal 010 has a leading zero, octal literals start with 0o at 1:1"},
+		{"puts(09)", "decimal literal 09 has a leading zero, octal literals start with 0o at 1:6"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		illegal := false
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			illegal = illegal || tok.Type == token.ILLEGAL
+		}
+		if !illegal {
+			t.Errorf("%q - no ILLEGAL token", tt.input)
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0] != tt.expected {
+			t.Errorf("%q - wrong errors. expected=%q, got=%v", tt.input, tt.expected, l.Errors())
+		}
+	}
+}
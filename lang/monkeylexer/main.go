@@ -5,10 +5,29 @@ import (
 	"os"
 	"os/user"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
+	"github.com/delavalom/arvlang/lang/monkeylexer/codegen"
+	"github.com/delavalom/arvlang/lang/monkeylexer/evaluator"
+	"github.com/delavalom/arvlang/lang/monkeylexer/formatter"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
 	"github.com/delavalom/arvlang/lang/monkeylexer/repl"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
 )
 
 func main() {
+	if len(os.Args) > 2 && os.Args[1] == "gen" {
+		genFile(os.Args[2])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == "fmt" {
+		formatFiles(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 {
+		runFile(os.Args[1])
+		return
+	}
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -17,3 +36,84 @@ func main() {
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// runFile evaluates a script, its imports are resolved relative to it
+func runFile(path string) {
+	env := value.NewModuleEnvironment(path)
+	evaluated, diagnostics := evaluator.EvalFile(path, env)
+	env.Session().Close()
+	if len(diagnostics) != 0 {
+		src, _ := os.ReadFile(path)
+		diagnostic.Render(os.Stderr, string(src), diagnostics...)
+		os.Exit(1)
+	}
+	if err, ok := evaluated.(*value.Error); ok {
+		// the error can come from an imported file, render the one it points into
+		src, _ := os.ReadFile(err.Span.File)
+		diagnostic.Render(os.Stderr, string(src), err.Diagnostic())
+		os.Exit(1)
+	}
+}
+
+// genFile prints the Go source generated from a script
+func genFile(path string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostics := p.Diagnostics()
+		for i := range diagnostics {
+			diagnostics[i].Span.File = path
+		}
+		diagnostic.Render(os.Stderr, string(src), diagnostics...)
+		os.Exit(1)
+	}
+	out, err := codegen.Generate(program, "main")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(out)
+}
+
+// formatFiles prints the formatted source of the scripts,
+// with -w the scripts are rewritten instead
+func formatFiles(args []string) {
+	write := args[0] == "-w"
+	if write {
+		args = args[1:]
+	}
+	failed := false
+	for _, path := range args {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		out, err := formatter.Source(string(src))
+		if syntaxErr, ok := err.(*formatter.SyntaxError); ok {
+			for i := range syntaxErr.Diagnostics {
+				syntaxErr.Diagnostics[i].Span.File = path
+			}
+			diagnostic.Render(os.Stderr, string(src), syntaxErr.Diagnostics...)
+			failed = true
+			continue
+		}
+		if !write {
+			os.Stdout.WriteString(out)
+		} else if out != string(src) {
+			if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
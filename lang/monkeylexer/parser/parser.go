@@ -1,8 +1,9 @@
 package parser
 
 import (
-	"fmt"
+	"unicode/utf8"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
 	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
 	"github.com/delavalom/arvlang/lang/monkeylexer/token"
@@ -11,8 +12,12 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	CONDITIONAL // c ? a : b
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
+	PIPE        // x |> f(y)
 	SUMMINUS    // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
@@ -21,16 +26,21 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
+	token.QUESTION: CONDITIONAL,
+	token.OR:       OR,
+	token.AND:      AND,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.PIPE:     PIPE,
 	token.PLUS:     SUMMINUS,
 	token.MINUS:    SUMMINUS,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
 
 type (
@@ -38,9 +48,27 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// maxErrors is the number of errors after which parsing is aborted
+const maxErrors = 10
+
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []diagnostic.Diagnostic
+	// lexerErrors counts the lexer errors already copied into errors
+	lexerErrors int
+	// panicking is set by the first error of a statement, the errors that
+	// follow it are cascades and are dropped until the parser synchronizes
+	panicking bool
+	// errorsAtEnd counts the errors found at the end of the input
+	errorsAtEnd int
+	// braces counts the braces open up to curToken, so synchronize can tell
+	// the end of a statement from a brace of a hash literal or a function
+	braces int
+
+	// functions counts the function literals being parsed, yields records
+	// whether the innermost one has a yield statement
+	functions int
+	yields    bool
 
 	curToken  token.Token
 	peekToken token.Token
@@ -52,7 +80,7 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []diagnostic.Diagnostic{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
@@ -61,6 +89,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
@@ -68,6 +97,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.IMPORT, p.parseImportExpression)
+	p.registerPrefix(token.TEMPLATE, p.parseTemplateLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -78,9 +110,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -92,6 +129,22 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braces++
+	case token.RBRACE:
+		p.braces--
+	}
+
+	diagnostics := p.l.Diagnostics()
+	for _, err := range diagnostics[p.lexerErrors:] {
+		p.lexerErrors++
+		// an unterminated string, template or comment is the last lexer error
+		atEnd := p.l.Incomplete() && p.lexerErrors == len(diagnostics)
+		if p.report(err) && atEnd {
+			p.errorsAtEnd++
+		}
+	}
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -112,34 +165,147 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// Errors returns the messages of the errors, with their position
 func (p *Parser) Errors() []string {
+	errors := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		errors[i] = err.Error()
+	}
+	return errors
+}
+
+// Diagnostics returns the errors found while parsing, lexer errors included
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
 	return p.errors
 }
 
+// TooManyErrors checks if the parser has reached maxErrors
+func (p *Parser) TooManyErrors() bool {
+	return len(p.errors) >= maxErrors
+}
+
+// Incomplete checks if every error was found at the end of the input,
+// where more input could complete the program, as in an unclosed brace,
+// a trailing operator or an unterminated string
+func (p *Parser) Incomplete() bool {
+	return len(p.errors) > 0 && p.errorsAtEnd == len(p.errors)
+}
+
+// report registers an error unless it is a cascade of a previous error
+// of the statement, the same error reported twice or one error too many,
+// it reports whether the error was registered. Only syntax errors cascade,
+// lexer errors are found independently of the statement being parsed
+func (p *Parser) report(d diagnostic.Diagnostic) bool {
+	syntax := d.Code == diagnostic.Syntax
+	if syntax && p.panicking || p.TooManyErrors() {
+		return false
+	}
+	if n := len(p.errors); n > 0 && p.errors[n-1].Message == d.Message && p.errors[n-1].Span == d.Span {
+		return false
+	}
+	p.errors = append(p.errors, d)
+	if syntax {
+		p.panicking = true
+	}
+
+	if p.TooManyErrors() {
+		p.errors = append(p.errors, diagnostic.Errorf(d.Span, diagnostic.Limit, "too many errors, aborting"))
+	}
+	return true
+}
+
+// errorAt registers a syntax error at the given token
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	if p.report(diagnostic.Errorf(tokenSpan(tok), diagnostic.Syntax, format, a...)) && tok.Type == token.EOF {
+		p.errorsAtEnd++
+	}
+}
+
+// tokenSpan returns the span of tok in the input, strings and templates
+// are decoded so only their first char is spanned
+func tokenSpan(tok token.Token) diagnostic.Span {
+	if tok.Type == token.STRING || tok.Type == token.TEMPLATE || tok.Type == token.EOF {
+		return diagnostic.Point(tok.Line, tok.Column)
+	}
+	return diagnostic.Span{
+		Start: diagnostic.Position{Line: tok.Line, Column: tok.Column},
+		End:   diagnostic.Position{Line: tok.Line, Column: tok.Column + max(utf8.RuneCountInString(tok.Literal), 1)},
+	}
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, "no prefix parse function for %s found", t)
+}
+
+// synchronize skips the tokens of a statement that failed to parse, which
+// started with depth braces open, up to the ; that ends it or the } that
+// closes the block around it, where parsing can resume
+func (p *Parser) synchronize(depth int) {
+	for !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.SEMICOLON) && p.braces == depth || p.curTokenIs(token.RBRACE) && p.braces < depth {
+			break
+		}
+		p.nextToken()
+	}
+	p.panicking = false
 }
 
+// endStatement consumes the optional ; that ends a statement, a statement
+// that failed to parse is left where it failed for synchronize to skip
+func (p *Parser) endStatement() {
+	if p.peekTokenIs(token.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+}
+
+// ParseProgram parses the statements of the program, a statement with
+// errors is left out of the program so the result is a partial AST
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
-	for !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		program.Statements = append(program.Statements, stmt)
+	for !p.curTokenIs(token.EOF) && !p.TooManyErrors() {
+		if stmt, ok := p.parseRecoverableStatement(); ok {
+			program.Statements = append(program.Statements, stmt)
+		}
 		p.nextToken()
 	}
 
 	return program
 }
 
+// parseRecoverableStatement parses a statement and reports false,
+// after synchronizing, when it has errors
+func (p *Parser) parseRecoverableStatement() (ast.Statement, bool) {
+	errors := len(p.errors)
+	// the error of a first token the lexer could not read was reported
+	// while it was the peek token of the previous statement
+	illegal := p.curTokenIs(token.ILLEGAL)
+	depth := p.braces
+	switch p.curToken.Type {
+	case token.LBRACE:
+		depth--
+	case token.RBRACE:
+		depth++
+	}
+	stmt := p.parseStatement()
+	if p.panicking {
+		p.synchronize(depth)
+		return nil, false
+	}
+	// the errors of a nested block were already synchronized in the block
+	// and lexer errors need no synchronizing, the statement is parsed to
+	// its end but is still left out
+	if len(p.errors) > errors || illegal {
+		return nil, false
+	}
+	return stmt, true
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
@@ -17,6 +18,7 @@ func TestLetStatements(t *testing.T) {
 		{"let x = 5;", "x", 5},
 		{"let y = true;", "y", true},
 		{"let foobar = y;", "foobar", "y"},
+		{"let user2Id = x1;", "user2Id", "x1"},
 	}
 
 	for _, tt := range tests {
@@ -140,6 +142,36 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775808", "9223372036854775808"},
+		{"0x1_0000_0000_0000_0000", "18446744073709551616"},
+		{"-99999999999999999999", "99999999999999999999"},
+	}
+
+	for _, tt := range tests {
+		program := New(lexer.New(tt.input)).ParseProgram()
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if prefix, ok := exp.(*ast.PrefixExpression); ok {
+			exp = prefix.Right
+		}
+		literal, ok := exp.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", exp)
+		}
+		if literal.Big == nil || literal.Big.String() != tt.expected {
+			t.Errorf("literal.Big not %s. got=%v", tt.expected, literal.Big)
+		}
+	}
+
+	if literal := New(lexer.New("5")).ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral); literal.Big != nil {
+		t.Errorf("literal in the int64 range has a Big value %s", literal.Big)
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -350,6 +382,62 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c",
+			"((a && b) || c)",
+		},
+		{
+			"a == b && !c",
+			"((a == b) && (!c))",
+		},
+		{
+			"a |> f(b) |> g",
+			"((a |> f(b)) |> g)",
+		},
+		{
+			"a + 1 |> f(b) == c",
+			"(((a + 1) |> f(b)) == c)",
+		},
+		{
+			"a < b |> f",
+			"(a < (b |> f))",
+		},
+		{
+			"a |> h.f(b)",
+			"(a |> (h.f)(b))",
+		},
+		{
+			"a.b.c",
+			"((a.b).c)",
+		},
+		{
+			"-a.b * c",
+			"((-(a.b)) * c)",
+		},
+		{
+			"a.b(c)[d].e",
+			"(((a.b)(c)[d]).e)",
+		},
+		{
+			"a || b ? c + d : -e",
+			"((a || b) ? (c + d) : (-e))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"f(a ? b : c, d)",
+			"f((a ? b : c), d)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -510,6 +598,146 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestConditionalExpression(t *testing.T) {
+	input := `x < y ? x : y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ConditionalExpression. got=%T", stmt.Expression)
+	}
+	testInfixExpression(t, exp.Condition, "x", "<", "y")
+	testIdentifier(t, exp.Consequence, "x")
+	testIdentifier(t, exp.Alternative, "y")
+}
+
+func TestConditionalInHashLiteral(t *testing.T) {
+	input := `{a ? "b" : "c": d ? 1 : 2}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	hash, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", program.Statements[0])
+	}
+	if len(hash.Keys) != 1 {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Keys))
+	}
+	key := hash.Keys[0]
+	if key.String() != `(a ? b : c)` {
+		t.Errorf("key is not %q. got=%q", `(a ? b : c)`, key.String())
+	}
+	if value := hash.Pairs[key].String(); value != "(d ? 1 : 2)" {
+		t.Errorf("value is not %q. got=%q", "(d ? 1 : 2)", value)
+	}
+}
+
+func TestConditionalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a ? b;", "expected next token to be :, got ; instead at 1:6"},
+		{"a ? b c", "expected next token to be :, got IDENT instead at 1:7"},
+		{"a ? : c", "no prefix parse function for : found at 1:5"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestForExpression(t *testing.T) {
+	input := `for x range xs { puts(x) }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.ForExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ForExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.Variable, "x")
+	testIdentifier(t, exp.Iterable, "xs")
+	if len(exp.Body.Statements) != 1 {
+		t.Errorf("body is not 1 statements. got=%d\n", len(exp.Body.Statements))
+	}
+}
+
+func TestYieldStatements(t *testing.T) {
+	input := `let count = fn(n) { let inner = fn() { n }; yield n; yield inner(); };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	function := stmt.Value.(*ast.FunctionLiteral)
+	if !function.Generator {
+		t.Errorf("function with yield is not a generator")
+	}
+	inner := function.Body.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if inner.Generator {
+		t.Errorf("function without yield nested in a generator is a generator")
+	}
+	yield, ok := function.Body.Statements[1].(*ast.YieldStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.YieldStatement. got=%T", function.Body.Statements[1])
+	}
+	testIdentifier(t, yield.Value, "n")
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1;", "yield outside of a function at 1:1"},
+		{"for 1 range xs {}", "expected next token to be IDENT, got INT instead at 1:5"},
+		{"for x in xs {}", "expected next token to be RANGE, got IDENT instead at 1:7"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -587,6 +815,61 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	input := "let f = fn(a, b = 2, c = a + b, ...rest) { a };"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+	if function.Name != "f" {
+		t.Errorf("function.Name wrong. want=%q, got=%q", "f", function.Name)
+	}
+	if len(function.Parameters) != 3 || len(function.Defaults) != 3 {
+		t.Fatalf("wrong parameters. got=%d parameters, %d defaults", len(function.Parameters), len(function.Defaults))
+	}
+	if function.Defaults[0] != nil {
+		t.Errorf("function.Defaults[0] is not nil. got=%s", function.Defaults[0])
+	}
+	testLiteralExpression(t, function.Defaults[1], 2)
+	testInfixExpression(t, function.Defaults[2], "a", "+", "b")
+	if function.Rest == nil || function.Rest.Value != "rest" {
+		t.Errorf("function.Rest wrong. got=%v", function.Rest)
+	}
+
+	expected := "fn(a, b = 2, c = (a + b), ...rest) a"
+	if function.String() != expected {
+		t.Errorf("function.String() wrong. want=%q, got=%q", expected, function.String())
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a = 1, b) {}", "parameter b without a default value follows parameters with one at 1:11"},
+		{"fn(...rest, a) {}", "expected next token to be ), got , instead at 1:11"},
+		{"fn(...) {}", "expected next token to be IDENT, got ) instead at 1:7"},
+		{"fn(1) {}", "expected next token to be IDENT, got INT instead at 1:4"},
+		{"fn(a, a) {}", "duplicate parameter a at 1:7"},
+		{"fn(a, b = 1, ...a) {}", "duplicate parameter a at 1:17"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -750,6 +1033,35 @@ func TestParsingArrayLiterals(t *testing.T) {
 	testInfixExpression(t, array.Elements[2], 3, "+", 3)
 }
 
+func TestParsingMemberExpressions(t *testing.T) {
+	input := "config.name"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	memberExp, ok := stmt.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.MemberExpression. got=%T", stmt.Expression)
+	}
+
+	testIdentifier(t, memberExp.Object, "config")
+	testIdentifier(t, memberExp.Property, "name")
+
+	p = New(lexer.New("config.1"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be IDENT, got INT instead at 1:8" {
+		t.Errorf("wrong errors for a member that is not a name. got=%v", p.Errors())
+	}
+}
+
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 
@@ -952,6 +1264,232 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	}
 }
 
+func TestImportExpression(t *testing.T) {
+	input := `let math = import "lib/math";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	imp, ok := stmt.Value.(*ast.ImportExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.ImportExpression. got=%T", stmt.Value)
+	}
+
+	if imp.Path != "lib/math" {
+		t.Errorf("imp.Path not %q. got=%q", "lib/math", imp.Path)
+	}
+}
+
+func TestTemplateLiteralParsing(t *testing.T) {
+	input := "`Hello ${name}, you are ${age + 1}\\${raw}`"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	tmpl, ok := stmt.Expression.(*ast.TemplateLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.TemplateLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(tmpl.Parts) != 5 {
+		t.Fatalf("tmpl.Parts has wrong length. got=%d", len(tmpl.Parts))
+	}
+	for i, text := range map[int]string{0: "Hello ", 2: ", you are ", 4: "${raw}"} {
+		lit, ok := tmpl.Parts[i].(*ast.StringLiteral)
+		if !ok || lit.Value != text {
+			t.Errorf("tmpl.Parts[%d] is not %q. got=%s", i, text, tmpl.Parts[i])
+		}
+	}
+	testIdentifier(t, tmpl.Parts[1], "name")
+	testInfixExpression(t, tmpl.Parts[3], "age", "+", 1)
+
+	expected := "`Hello ${name}, you are ${(age + 1)}\\${raw}`"
+	if tmpl.String() != expected {
+		t.Errorf("tmpl.String() wrong. expected=%q, got=%q", expected, tmpl.String())
+	}
+}
+
+func TestTemplateLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`${}`", "empty ${} in template at 1:2"},
+		{"`${a b}`", "unexpected IDENT in template interpolation at 1:6"},
+		{"`x\n  ${1 +}`", "no prefix parse function for EOF found at 2:8"},
+		{"`${a`", "unterminated template at 1:1"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = "open`, "unterminated string at 1:9"},
+		{`puts("\q");`, `invalid escape sequence \q at 1:7`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) != 1 || p.Errors()[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input      string
+		statements []string
+		errors     []string
+	}{
+		{
+			"let = 5; let y = 10; let z = ;",
+			[]string{"let y = 10;"},
+			[]string{
+				"expected next token to be IDENT, got = instead at 1:5",
+				"no prefix parse function for ; found at 1:30",
+			},
+		},
+		{
+			"let x 5 * 5 * 5; x;",
+			[]string{"x"},
+			[]string{"expected next token to be =, got INT instead at 1:7"},
+		},
+		{
+			"if (x) { let = 1; y } z;",
+			[]string{"z"},
+			[]string{"expected next token to be IDENT, got = instead at 1:14"},
+		},
+		{
+			"let f = fn(x) { x + }; f(1);",
+			[]string{"f(1)"},
+			[]string{"no prefix parse function for } found at 1:21"},
+		},
+		{
+			`let h = {"a": }; let y = 1; y`,
+			[]string{"let y = 1;", "y"},
+			[]string{"no prefix parse function for } found at 1:15"},
+		},
+		{
+			"let f = fn() { let g = fn() { 1 + }; g() }; f()",
+			[]string{"f()"},
+			[]string{"no prefix parse function for } found at 1:35"},
+		},
+		{
+			"} let x = 1;",
+			[]string{"let x = 1;"},
+			[]string{"no prefix parse function for } found at 1:1"},
+		},
+		{
+			`let x = (1 + ) * "\q"; let y = 1; y`,
+			[]string{"let y = 1;", "y"},
+			[]string{
+				"no prefix parse function for ) found at 1:14",
+				"invalid escape sequence \\q at 1:19",
+			},
+		},
+		{
+			`let a = ; "\q"; let y = 1; y`,
+			[]string{"let y = 1;", "y"},
+			[]string{
+				"invalid escape sequence \\q at 1:12",
+				"no prefix parse function for ; found at 1:9",
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		if len(program.Statements) != len(tt.statements) {
+			t.Fatalf("tests[%d] - wrong number of statements. expected=%d, got=%d (%s)",
+				i, len(tt.statements), len(program.Statements), program.String())
+		}
+		for j, stmt := range program.Statements {
+			if stmt == nil || stmt.String() != tt.statements[j] {
+				t.Errorf("tests[%d] - statements[%d] wrong. expected=%q, got=%v", i, j, tt.statements[j], stmt)
+			}
+		}
+
+		errors := p.Errors()
+		if len(errors) != len(tt.errors) {
+			t.Fatalf("tests[%d] - wrong number of errors. expected=%q, got=%q", i, tt.errors, errors)
+		}
+		for j, err := range errors {
+			if err != tt.errors[j] {
+				t.Errorf("tests[%d] - errors[%d] wrong. expected=%q, got=%q", i, j, tt.errors[j], err)
+			}
+		}
+	}
+}
+
+func TestTooManyErrors(t *testing.T) {
+	input := strings.Repeat("let = 1;\n", 20)
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	if !p.TooManyErrors() {
+		t.Fatalf("p.TooManyErrors() is false with %d errors", len(p.Errors()))
+	}
+	errors := p.Diagnostics()
+	if len(errors) != maxErrors+1 {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d", maxErrors+1, len(errors))
+	}
+	if errors[maxErrors-1].Span.Start.Line != maxErrors {
+		t.Errorf("last error at wrong line. expected=%d, got=%d", maxErrors, errors[maxErrors-1].Span.Start.Line)
+	}
+	if errors[maxErrors].Message != "too many errors, aborting" {
+		t.Errorf("wrong final error. got=%q", errors[maxErrors].Message)
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"let x = 5;", false},
+		{"fn(x) {", true},
+		{"add(1,", true},
+		{"[1, 2", true},
+		{"{\"a\": 1", true},
+		{"5 *", true},
+		{"let x", true},
+		{`"open`, true},
+		{"`open ${", true},
+		{"/* open", true},
+		{"let = 5; fn(x) {", false},
+		{"5 * );", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if p.Incomplete() != tt.incomplete {
+			t.Errorf("p.Incomplete() wrong for %q. expected=%t, errors=%v", tt.input, tt.incomplete, p.Errors())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -1,13 +1,37 @@
 package parser
 
 import (
-	"fmt"
+	"errors"
+	"math/big"
 	"strconv"
+	"strings"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
 	"github.com/delavalom/arvlang/lang/monkeylexer/token"
 )
 
+// parseYieldStatement parses a yield statement, which makes the function
+// it is in a generator
+// yield <expression>;
+// example: yield x * 2;
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{Token: p.curToken}
+	if p.functions == 0 {
+		p.errorAt(p.curToken, "yield outside of a function")
+	}
+	p.yields = true
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	p.endStatement()
+
+	return stmt
+}
+
 // parseExpressionStatement parses an expression statement
 // either a let statement, a return statement or an expression statement
 // <expression>;
@@ -18,6 +42,8 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -42,11 +68,13 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
-
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		// the function is named after its binding in errors
+		fn.Name = stmt.Name.Value
 	}
 
+	p.endStatement()
+
 	return stmt
 }
 
@@ -60,9 +88,7 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
-	}
+	p.endStatement()
 
 	return stmt
 }
@@ -75,9 +101,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 	stmt.Expression = p.parseExpression(LOWEST)
 
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
-	}
+	p.endStatement()
 
 	return stmt
 }
@@ -107,6 +131,15 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
+// Precedence returns the precedence of the infix operator t,
+// LOWEST when t is not an infix operator
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
+}
+
 func (p *Parser) peekPrecedence() int {
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
@@ -122,16 +155,22 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
-// parseIntegerLiteral parses an integer
+// parseIntegerLiteral parses an integer, one out of the int64 range is
+// kept as a big.Int
 // <integer>
 // example: 5
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if n, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = n
+			return lit
+		}
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -147,6 +186,12 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseIllegal skips a token the lexer could not read,
+// the lexer already registered the error
+func (p *Parser) parseIllegal() ast.Expression {
+	return nil
+}
+
 // parsePrefixExpression parses a prefix expression
 // <operator><expression>
 // example: -5
@@ -245,6 +290,53 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseConditionalExpression parses a conditional, the alternative is parsed
+// below CONDITIONAL so that conditionals nest to the right
+// <expression> ? <expression> : <expression>
+// example: x < y ? x : y
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Alternative = p.parseExpression(CONDITIONAL - 1)
+
+	return expression
+}
+
+// parseForExpression parses a for range loop
+// for <identifier> range <expression> { <block statement> }
+// example: for x range [1, 2, 3] { puts(x); }
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.RANGE) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
 // parseBlockStatement parses everything between { and }
 // { <statement>; <statement>; ...; }
 // example: { let x = 5; let y = 10; }
@@ -255,17 +347,25 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
+		stmt, ok := p.parseRecoverableStatement()
+		if ok {
+			block.Statements = append(block.Statements, stmt)
+		} else if p.curTokenIs(token.RBRACE) {
+			// synchronized on the brace that closes the block
+			break
+		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.EOF) {
+		p.errorAt(p.curToken, "expected %s to close the block, got EOF instead", token.RBRACE)
+	}
 
 	return block
 }
 
 // parseFunctionLiteral parsers a function
-// fn <ident>or<(<ident>,<ident>,...>) { <block statement> }
-// example: fn(x, y) { return x + y; }
+// fn(<ident>, <ident> = <expression>, ...<ident>) { <block statement> }
+// example: fn(x, y = 1, ...rest) { return x + y; }
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
@@ -273,42 +373,88 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	yields := p.yields
+	p.functions++
+	p.yields = false
 	lit.Body = p.parseBlockStatement()
+	lit.Generator = p.yields
+	p.functions--
+	p.yields = yields
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionParameters parses the parameters of lit up to the closing ),
+// the parameters with a default value come after the others
+// and the ...rest parameter comes last
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
-	p.nextToken()
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.uniqueParameter(lit, lit.Rest) {
+				return false
+			}
+			return p.expectPeek(token.RPAREN)
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.uniqueParameter(lit, ident) {
+			return false
+		}
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+		} else if n := len(lit.Defaults); n > 0 && lit.Defaults[n-1] != nil {
+			p.errorAt(ident.Token, "parameter %s without a default value follows parameters with one", ident.Value)
+			return false
+		}
+		lit.Parameters = append(lit.Parameters, ident)
+		lit.Defaults = append(lit.Defaults, def)
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+	return p.expectPeek(token.RPAREN)
+}
 
-	return identifiers
+// uniqueParameter reports an error when a parameter of lit is already
+// named as ident
+func (p *Parser) uniqueParameter(lit *ast.FunctionLiteral, ident *ast.Identifier) bool {
+	for _, param := range lit.Parameters {
+		if param.Value == ident.Value {
+			p.errorAt(ident.Token, "duplicate parameter %s", ident.Value)
+			return false
+		}
+	}
+	return true
 }
 
 // parseCallExpression parses a function call
@@ -374,6 +520,20 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseMemberExpression parses a member access
+// <expression>.<identifier>
+// example: config.name or module.function
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 // parseHashLiteral parses a hash
 // { <expression>: <expression>, <expression>: <expression>, ... }
 // example: { "key": "value", "key2": "value2" }
@@ -393,6 +553,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -405,3 +566,119 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 	return hash
 }
+
+// parseImportExpression parses an import of another source file
+// import "<path>"
+// example: import "lib/math"
+func (p *Parser) parseImportExpression() ast.Expression {
+	exp := &ast.ImportExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	exp.Path = p.curToken.Literal
+
+	return exp
+}
+
+// parseTemplateLiteral parses a template string
+// `<text>${<expression>}<text>...`
+// example: `hello ${name}!`
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	tmpl := &ast.TemplateLiteral{Token: p.curToken}
+	raw := p.curToken.Literal
+
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			lit := text.String()
+			tmpl.Parts = append(tmpl.Parts, &ast.StringLiteral{
+				Token: token.Token{Type: token.STRING, Literal: lit},
+				Value: lit,
+			})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && i+1 < len(raw) && strings.IndexByte("\\`$", raw[i+1]) >= 0:
+			i++
+			text.WriteByte(raw[i])
+		case raw[i] == '$' && i+1 < len(raw) && raw[i+1] == '{':
+			end := lexer.InterpolationEnd(raw, i+2)
+			if end < 0 {
+				p.errorAt(tmpl.Token, "unterminated ${ in template")
+				return nil
+			}
+			flush()
+			line, column := advancePosition(tmpl.Token.Line, tmpl.Token.Column+1, raw[:i+2])
+			exp := p.parseInterpolation(raw[i+2:end], line, column)
+			if exp == nil {
+				return nil
+			}
+			tmpl.Parts = append(tmpl.Parts, exp)
+			i = end
+		default:
+			text.WriteByte(raw[i])
+		}
+	}
+	flush()
+
+	return tmpl
+}
+
+// parseInterpolation parses the source between ${ and } as one expression,
+// line and column are the position of src in the input
+func (p *Parser) parseInterpolation(src string, line, column int) ast.Expression {
+	if strings.TrimSpace(src) == "" {
+		span := diagnostic.Span{
+			Start: diagnostic.Position{Line: line, Column: column - 2},
+			End:   diagnostic.Position{Line: line, Column: column + len(src) + 1},
+		}
+		p.report(diagnostic.Errorf(span, diagnostic.Syntax, "empty ${} in template"))
+		return nil
+	}
+
+	sub := New(lexer.New(src))
+	exp := sub.parseExpression(LOWEST)
+	if !sub.peekTokenIs(token.EOF) {
+		sub.errorAt(sub.peekToken, "unexpected %s in template interpolation", sub.peekToken.Type)
+	}
+	if len(sub.errors) != 0 {
+		for _, err := range sub.errors {
+			err.Span = rebaseSpan(err.Span, line, column)
+			p.report(err)
+		}
+		return nil
+	}
+
+	return exp
+}
+
+// rebaseSpan moves a span relative to the start of an interpolation
+// to the interpolation's position in the input
+func rebaseSpan(span diagnostic.Span, line, column int) diagnostic.Span {
+	rebase := func(pos diagnostic.Position) diagnostic.Position {
+		if pos.Line == 1 {
+			return diagnostic.Position{Line: line, Column: column + pos.Column - 1}
+		}
+		return diagnostic.Position{Line: line + pos.Line - 1, Column: pos.Column}
+	}
+	span.Start, span.End = rebase(span.Start), rebase(span.End)
+	return span
+}
+
+// advancePosition returns the position reached after reading text
+// from the given line and column
+func advancePosition(line, column int, text string) (int, int) {
+	for _, ch := range text {
+		if ch == '\n' {
+			line++
+			column = 0
+		}
+		column++
+	}
+	return line, column
+}
@@ -0,0 +1,134 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
+	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/evaluator"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+// session is the state a REPL keeps between inputs
+type session struct {
+	env *value.Environment
+	out io.Writer
+}
+
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, arg string)
+}
+
+// commands are the meta-commands of the REPL, typed as :name arg
+var commands = map[string]command{
+	"env":    {":env", "list the bindings of the session", (*session).env_},
+	"ast":    {":ast <code>", "print the parsed program", (*session).ast},
+	"tokens": {":tokens <code>", "print the tokens of the code", (*session).tokens},
+	"load":   {":load <file>", "evaluate a file into the session", (*session).load},
+	"reset":  {":reset", "drop every binding of the session", (*session).reset},
+	"type":   {":type <code>", "print the type of the value of the code", (*session).type_},
+}
+
+func init() {
+	// help lists commands, it is added here to avoid an initialization cycle
+	commands["help"] = command{":help", "list the commands", (*session).help}
+}
+
+// runCommand runs the meta-command in line, without its leading colon
+func (s *session) runCommand(line string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command :%s, :help lists the commands\n", name)
+		return
+	}
+	cmd.run(s, strings.TrimSpace(arg))
+}
+
+func (s *session) help(string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%-16s %s\n", commands[name].usage, commands[name].help)
+	}
+}
+
+func (s *session) env_(string) {
+	for _, name := range s.env.Names() {
+		val, _ := s.env.Get(name)
+		fmt.Fprintf(s.out, "%s: %s = %s\n", name, val.Type(), val.Inspect())
+	}
+}
+
+func (s *session) ast(src string) {
+	if program := s.parse(src); program != nil {
+		fmt.Fprintln(s.out, program.String())
+	}
+}
+
+func (s *session) tokens(src string) {
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%d:%d %s %q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+	for _, err := range l.Errors() {
+		fmt.Fprintln(s.out, err)
+	}
+}
+
+func (s *session) load(path string) {
+	if path == "" {
+		fmt.Fprintln(s.out, "usage: :load <file>")
+		return
+	}
+	evaluated, diagnostics := evaluator.EvalFile(path, s.env)
+	if len(diagnostics) != 0 {
+		src, _ := os.ReadFile(path)
+		diagnostic.Render(s.out, string(src), diagnostics...)
+		return
+	}
+	if evaluated != nil && evaluated.Type() == value.ERROR_VAL {
+		fmt.Fprintln(s.out, evaluated.Inspect())
+	}
+}
+
+func (s *session) reset(string) {
+	s.env.Session().Close()
+	s.env = value.NewEnvironment()
+}
+
+func (s *session) type_(src string) {
+	program := s.parse(src)
+	if program == nil {
+		return
+	}
+	evaluated := evaluator.Eval(program, s.env)
+	if evaluated == nil {
+		fmt.Fprintln(s.out, "no value")
+		return
+	}
+	fmt.Fprintln(s.out, evaluated.Type())
+}
+
+// parse parses src rendering its errors, it returns nil when there are errors
+func (s *session) parse(src string) *ast.Program {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostic.Render(s.out, src, p.Diagnostics()...)
+		return nil
+	}
+	return program
+}
@@ -0,0 +1,281 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/evaluator"
+	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
+)
+
+// errInterrupted is returned by readLine when the line is dropped with Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the lines typed in the REPL
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// newLineReader returns a line editor when in is a terminal
+// and a plain line scanner otherwise
+func newLineReader(in io.Reader, out io.Writer, s *session) lineReader {
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		return &editor{
+			in:       bufio.NewReader(f),
+			out:      out,
+			raw:      func() (func(), error) { return makeRaw(int(f.Fd())) },
+			history:  loadHistory(historyFile()),
+			complete: s.complete,
+		}
+	}
+	return &scanner{scanner: bufio.NewScanner(in), out: out}
+}
+
+// scanner reads lines without editing, for input that is not a terminal
+type scanner struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (s *scanner) readLine(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.scanner.Text(), nil
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// editor reads lines from a terminal in raw mode, with cursor movement,
+// history browsing and tab completion
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// raw puts the terminal in raw mode and returns the function restoring it
+	raw     func() (func(), error)
+	history *history
+	// complete returns the start of the word before pos and its completions
+	complete func(line []rune, pos int) (int, []string)
+
+	line []rune
+	pos  int
+}
+
+func (e *editor) readLine(prompt string) (string, error) {
+	restore, err := e.raw()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.line, e.pos = nil, 0
+	fmt.Fprint(e.out, prompt)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+			line := string(e.line)
+			e.history.add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			e.history.add("")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			e.delete(e.pos)
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.pos--
+				e.delete(e.pos)
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlB:
+			e.pos = max(e.pos-1, 0)
+		case keyCtrlF:
+			e.pos = min(e.pos+1, len(e.line))
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = append([]rune{}, e.line[e.pos:]...)
+			e.pos = 0
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyCtrlP:
+			e.browse(e.history.prev(string(e.line)))
+		case keyCtrlN:
+			e.browse(e.history.next())
+		case keyTab:
+			e.completeWord()
+		case keyEscape:
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(r)
+			}
+		}
+		e.redraw(prompt)
+	}
+}
+
+// escape handles the escape sequences of the arrow, home, end and delete keys
+func (e *editor) escape() {
+	if r, _, _ := e.in.ReadRune(); r != '[' && r != 'O' {
+		return
+	}
+	code, _, _ := e.in.ReadRune()
+	if '0' <= code && code <= '9' {
+		// the sequence is a number ended by ~
+		if end, _, _ := e.in.ReadRune(); end != '~' {
+			return
+		}
+	}
+
+	switch code {
+	case 'A':
+		e.browse(e.history.prev(string(e.line)))
+	case 'B':
+		e.browse(e.history.next())
+	case 'C':
+		e.pos = min(e.pos+1, len(e.line))
+	case 'D':
+		e.pos = max(e.pos-1, 0)
+	case 'H', '1', '7':
+		e.pos = 0
+	case 'F', '4', '8':
+		e.pos = len(e.line)
+	case '3':
+		e.delete(e.pos)
+	}
+}
+
+func (e *editor) insert(r rune) {
+	e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+	e.pos++
+}
+
+func (e *editor) delete(pos int) {
+	if pos < len(e.line) {
+		e.line = append(e.line[:pos], e.line[pos+1:]...)
+	}
+}
+
+// browse replaces the line with a history entry
+func (e *editor) browse(line string, ok bool) {
+	if ok {
+		e.line = []rune(line)
+		e.pos = len(e.line)
+	}
+}
+
+// completeWord completes the word before the cursor with the longest prefix
+// its completions share, the completions are listed when it is ambiguous
+func (e *editor) completeWord() {
+	start, candidates := e.complete(e.line, e.pos)
+	if len(candidates) == 0 {
+		return
+	}
+
+	word := string(e.line[start:e.pos])
+	prefix := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		prefix += " "
+	}
+	if prefix != word {
+		for _, r := range strings.TrimPrefix(prefix, word) {
+			e.insert(r)
+		}
+		return
+	}
+	fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+}
+
+// redraw writes the prompt and the line over the current line of the
+// terminal and moves the cursor back to its position
+func (e *editor) redraw(prompt string) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(e.line))
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// complete returns the start of the word before pos in line and the keywords,
+// builtins and session bindings it begins, or the commands after a colon
+func (s *session) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && (unicode.IsLetter(line[start-1]) || unicode.IsDigit(line[start-1]) || line[start-1] == '_') {
+		start--
+	}
+	word := string(line[start:pos])
+
+	var names []string
+	if start == 1 && line[0] == ':' {
+		for name := range commands {
+			names = append(names, name)
+		}
+	} else {
+		names = append(names, lexer.Keywords()...)
+		names = append(names, evaluator.BuiltinNames()...)
+		names = append(names, s.env.Names()...)
+	}
+
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, word) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return start, candidates
+}
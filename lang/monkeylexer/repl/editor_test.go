@@ -0,0 +1,90 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/delavalom/arvlang/lang/monkeylexer/value"
+)
+
+func testEditor(keys string, h *history) *editor {
+	s := &session{env: value.NewEnvironment(), out: io.Discard}
+	s.env.Set("counter", &value.Integer{Value: 1})
+	return &editor{
+		in:       bufio.NewReader(strings.NewReader(keys)),
+		out:      io.Discard,
+		raw:      func() (func(), error) { return func() {}, nil },
+		history:  h,
+		complete: s.complete,
+	}
+}
+
+func TestEditorReadLine(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"let x = 1;\r", "let x = 1;"},
+		{"ab\x1b[Dc\r", "acb"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abx\x7f\r", "ab"},
+		{"abc\x1b[D\x1b[D\x1b[3~\r", "ac"},
+		{"abc\x1b[H\x0b\r", ""},
+		{"abc\x1b[D\x15\r", "c"},
+		{"été\x7f\r", "ét"},
+		{"cou\t\r", "counter "},
+		{"ret\t\r", "return "},
+		{"le\t\r", "le"},
+		{":lo\t\r", ":load "},
+		{"las\t[1]\r", "last [1]"},
+		{"pu\tt\t\r", "puts "},
+	}
+
+	for i, tt := range tests {
+		e := testEditor(tt.keys, loadHistory(""))
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Fatalf("tests[%d] - readLine error: %s", i, err)
+		}
+		if line != tt.expected {
+			t.Errorf("tests[%d] - wrong line. expected=%q, got=%q", i, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), HISTORY_FILE)
+	e := testEditor("one\rtwo\rtwo\r\x1b[A\x1b[A\r\x1b[A\x1b[A\x1b[Bx\r", loadHistory(file))
+
+	expected := []string{"one", "two", "two", "one", "onex"}
+	for i, want := range expected {
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Fatalf("line %d - readLine error: %s", i, err)
+		}
+		if line != want {
+			t.Errorf("line %d - wrong line. expected=%q, got=%q", i, want, line)
+		}
+	}
+
+	h := loadHistory(file)
+	entries := strings.Join(h.entries, ",")
+	if entries != "one,two,one,onex" {
+		t.Errorf("wrong history file entries. got=%q", entries)
+	}
+}
+
+func TestEditorControlKeys(t *testing.T) {
+	e := testEditor("abc\x03\x04", loadHistory(""))
+
+	if _, err := e.readLine(PROMPT); !errors.Is(err, errInterrupted) {
+		t.Errorf("Ctrl-C did not interrupt. got=%v", err)
+	}
+	if _, err := e.readLine(PROMPT); err != io.EOF {
+		t.Errorf("Ctrl-D on an empty line did not end the input. got=%v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// HISTORY_FILE is the dotfile, in the home directory, the history is kept in
+	HISTORY_FILE = ".monkey_history"
+	// maxHistory is the number of lines the history remembers
+	maxHistory = 1000
+)
+
+// history holds the lines entered in the REPL, the ones of past sessions
+// are loaded from its file and the new ones are appended to it
+type history struct {
+	file    string
+	entries []string
+	// pos is the entry being browsed, len(entries) when on the line being typed
+	pos int
+	// draft is the line being typed, kept while browsing the entries
+	draft string
+}
+
+// historyFile returns the path of the history dotfile, empty when there is
+// no home directory to keep it in
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// loadHistory reads the history kept in file, an empty file name gives
+// a history that is not persisted
+func loadHistory(file string) *history {
+	h := &history{file: file}
+	if f, err := os.Open(file); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			h.entries = append(h.entries, scanner.Text())
+		}
+		f.Close()
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.pos = len(h.entries)
+	return h
+}
+
+// add appends line to the history unless it is blank or repeats the last entry
+func (h *history) add(line string) {
+	h.pos = len(h.entries)
+	if strings.TrimSpace(line) == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+	h.pos = len(h.entries)
+
+	if h.file == "" {
+		return
+	}
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
+
+// prev returns the entry before the one being browsed, current is
+// the line being edited and is kept as the draft when browsing starts
+func (h *history) prev(current string) (string, bool) {
+	if h.pos == 0 {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// next returns the entry after the one being browsed, or the draft
+// when going past the last entry
+func (h *history) next() (string, bool) {
+	if h.pos == len(h.entries) {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
@@ -1,45 +1,67 @@
 package repl
 
 import (
-	"bufio"
-	"fmt"
+	"errors"
 	"io"
+	"strings"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/monkeylexer/evaluator"
 	"github.com/delavalom/arvlang/lang/monkeylexer/lexer"
 	"github.com/delavalom/arvlang/lang/monkeylexer/parser"
 	"github.com/delavalom/arvlang/lang/monkeylexer/value"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT = ">> "
+	// CONTINUATION_PROMPT is shown while the input is incomplete,
+	// as in an unclosed brace, a trailing operator or an open string
+	CONTINUATION_PROMPT = ".. "
+)
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := value.NewEnvironment()
+	s := &session{env: value.NewEnvironment(), out: out}
+	// the generators left suspended by the session stop with it
+	defer func() { s.env.Session().Close() }()
+	reader := newLineReader(in, out, s)
+	var input strings.Builder
 	for {
-		fmt.Print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		prompt := PROMPT
+		if input.Len() != 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+		line, err := reader.readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			input.Reset()
+			continue
+		}
+		if err != nil {
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+		if input.Len() == 0 && strings.HasPrefix(line, ":") {
+			s.runCommand(line[1:])
+			continue
+		}
+		// a blank line gives up on completing the input and reports its errors
+		force := input.Len() != 0 && strings.TrimSpace(line) == ""
+		input.WriteString(line)
+		input.WriteString("\n")
+
+		src := input.String()
+		p := parser.New(lexer.New(src))
 		program := p.ParseProgram()
+		if p.Incomplete() && !force {
+			continue
+		}
+		input.Reset()
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			diagnostic.Render(out, src, p.Diagnostics()...)
 			continue
 		}
-		evaluated := evaluator.Eval(program, env)
+		evaluated := evaluator.Eval(program, s.env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
 	}
 }
-
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
-	}
-}
@@ -0,0 +1,118 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultiLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2\n", ">> 3\n>> "},
+		{"let add = fn(a, b) {\n  a + b\n};\nadd(1, 2)\n", ">> .. .. >> 3\n>> "},
+		{"[1,\n2][1]\n", ">> .. 2\n>> "},
+		{"1 +\n2\n", ">> .. 3\n>> "},
+		{"\"multi\nline\"\n", ">> .. multi\nline\n>> "},
+		{"/* a\ncomment */ 5\n", ">> .. 5\n>> "},
+	}
+
+	for i, tt := range tests {
+		var out strings.Builder
+		Start(strings.NewReader(tt.input), &out)
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - wrong output. expected=%q, got=%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestIncompleteInputErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// a blank line reports the errors of the incomplete input
+		{"fn(x) {\n\n", "expected } to close the block, got EOF instead"},
+		// errors before the end are reported right away
+		{"let = 1; fn(x) {\n", "expected next token to be IDENT, got = instead"},
+	}
+
+	for i, tt := range tests {
+		var out strings.Builder
+		Start(strings.NewReader(tt.input), &out)
+		if !strings.Contains(out.String(), tt.expected) {
+			t.Errorf("tests[%d] - output does not report %q. got=%q", i, tt.expected, out.String())
+		}
+		if !strings.HasSuffix(out.String(), PROMPT) {
+			t.Errorf("tests[%d] - input was not reset. got=%q", i, out.String())
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lib.monkey")
+	if err := os.WriteFile(path, []byte("let double = fn(x) { x * 2 };"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a loaded file resolves its imports relative to itself
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "lib", "main.monkey")
+	if err := os.WriteFile(main, []byte(`let two = import "consts".two;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "consts.monkey"), []byte("let two = 2;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1;\nlet b = \"x\";\n:env\n", "a: INTEGER = 1\nb: STRING = x\n"},
+		{":ast 1 + 2 * 3\n", "(1 + (2 * 3))\n"},
+		{":tokens let x\n", "1:1 LET \"let\"\n1:5 IDENT \"x\"\n"},
+		{":type [1]\n", "ARRAY\n"},
+		{"let a = 1;\n:reset\n:env\na\n", "ERROR: identifier not found: a\n"},
+		{":load " + path + "\ndouble(4)\n", "8\n"},
+		{":load " + main + "\ntwo\n", "2\n"},
+		{":nope\n", "unknown command :nope, :help lists the commands\n"},
+	}
+
+	for i, tt := range tests {
+		var out strings.Builder
+		Start(strings.NewReader(tt.input), &out)
+		got := strings.ReplaceAll(out.String(), PROMPT, "")
+		if got != tt.expected {
+			t.Errorf("tests[%d] - wrong output. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestSuspendedGeneratorsStop(t *testing.T) {
+	tests := []string{
+		"let g = fn() { yield 1; yield 2; }; let it = g(); next(it)\n",
+		"let g = fn() { yield 1; yield 2; }; let it = g(); next(it)\n:reset\nlet a = 1;\n",
+	}
+
+	for i, input := range tests {
+		before := runtime.NumGoroutine()
+		var out strings.Builder
+		Start(strings.NewReader(input), &out)
+
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("tests[%d] - generator outlived the session. before=%d, after=%d", i, before, after)
+		}
+	}
+}
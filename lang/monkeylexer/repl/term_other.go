@@ -0,0 +1,15 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// isTerminal reports false, line editing is only supported on linux
+// and the REPL reads plain lines elsewhere
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
@@ -5,6 +5,8 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int
 }
 
 const (
@@ -24,6 +26,10 @@ const (
 	NOT_EQ   = "!="
 	LT       = "<"
 	GT       = ">"
+	AND      = "&&"
+	OR       = "||"
+	PIPE     = "|>"
+	QUESTION = "?" // c ? a : b
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
@@ -42,8 +48,17 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IMPORT   = "IMPORT"
+	FOR      = "FOR"
+	RANGE    = "RANGE"
+	YIELD    = "YIELD"
 
-	STRING = "STRING"
+	STRING   = "STRING"
+	TEMPLATE = "TEMPLATE" // `text ${expression}`
 
-	COLON = ":" // for hash literals
+	COLON    = ":"   // for hash literals and conditionals
+	DOT      = "."   // for member access
+	ELLIPSIS = "..." // for variadic parameters
+
+	COMMENT = "COMMENT" // kept as trivia, see Lexer.Comments
 )
@@ -1,13 +1,28 @@
 package value
 
+import "sort"
+
+// NewEnvironment creates the top-level environment of a new session
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, session: NewSession()}
+}
+
+// NewModuleEnvironment creates the top-level environment of a source file
+// run in a new session, file is used to resolve the imports made from that file
+func NewModuleEnvironment(file string) *Environment {
+	env := NewEnvironment()
+	env.file = file
+	return env
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store   map[string]Object
+	outer   *Environment
+	file    string
+	session *Session
+	// yield sends the values of the generator call the environment is for
+	yield func(Object) bool
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -22,8 +37,60 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Names returns the sorted names bound in this environment,
+// without the ones of the outer environments
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// File returns the source file the environment belongs to, looking it up
+// in the outer environments, or "" when the code does not come from a file
+func (e *Environment) File() string {
+	if e.file == "" && e.outer != nil {
+		return e.outer.File()
+	}
+	return e.file
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
+	env := &Environment{store: make(map[string]Object), outer: outer, session: outer.session}
 	return env
 }
+
+// ModuleEnvironment creates the top-level environment of a file imported
+// from e, in the session of e
+func (e *Environment) ModuleEnvironment(file string) *Environment {
+	env := &Environment{store: make(map[string]Object), file: file, session: e.session}
+	return env
+}
+
+// WithFile returns e for the code of file, the bindings made in it are the
+// ones of e but the imports are resolved relative to file
+func (e *Environment) WithFile(file string) *Environment {
+	env := *e
+	env.file = file
+	return &env
+}
+
+// Session returns the session the environment belongs to
+func (e *Environment) Session() *Session {
+	return e.session
+}
+
+// SetYield makes the environment the one of a generator call, yield sends
+// a value to the consumer and returns false when the generator is closed
+func (e *Environment) SetYield(yield func(Object) bool) {
+	e.yield = yield
+}
+
+// Yield returns the yield function of the generator call the environment
+// is for, nil when it is not one. The outer environments are not searched,
+// a function inside a generator is not part of it
+func (e *Environment) Yield() func(Object) bool {
+	return e.yield
+}
@@ -0,0 +1,61 @@
+package value
+
+import "math/big"
+
+// Equal reports whether a and b are the same value: integers by their
+// number whatever their representation, strings, booleans and nil by
+// value, arrays element by element and hashes by their pairs, in any
+// order. Functions, builtins and modules are only equal to themselves
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *BigInteger:
+			return big.NewInt(a.Value).Cmp(b.Value) == 0
+		}
+		return false
+	case *BigInteger:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value.Cmp(big.NewInt(b.Value)) == 0
+		case *BigInteger:
+			return a.Value.Cmp(b.Value) == 0
+		}
+		return false
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Nil:
+		_, ok := b.(*Nil)
+		return ok
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !Equal(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			other, ok := b.Get(pair.Key)
+			if !ok || !Equal(pair.Value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,100 @@
+package value
+
+const (
+	generatorNew = iota
+	generatorSuspended
+	generatorDone
+)
+
+// Generator is the lazy sequence of the values yielded by a call to a
+// generator function. The function runs in a goroutine of its own, one
+// yield at a time as Next asks for values, and Close stops it when the
+// consumer leaves before the end. The goroutine of a generator that is
+// never closed keeps running until the session tracking it is closed
+type Generator struct {
+	Name string
+
+	session *Session
+	state   int
+	run     func(yield func(Object) bool) Object
+	values  chan Object
+	resume  chan bool
+}
+
+// NewGenerator returns the generator of the values run passes to yield,
+// run stops when yield returns false and its result is only used when it
+// is an error, which is then the last value of the generator
+func NewGenerator(name string, run func(yield func(Object) bool) Object) *Generator {
+	return &Generator{Name: name, run: run, values: make(chan Object), resume: make(chan bool)}
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_VAL }
+func (g *Generator) Inspect() string  { return "<generator " + g.Name + ">" }
+
+// start runs the function in its goroutine, the session keeps the
+// generator until it is done
+func (g *Generator) start() {
+	if g.session != nil {
+		g.session.generators[g] = true
+	}
+	values, resume, run := g.values, g.resume, g.run
+	go func() {
+		defer close(values)
+		stopped := false
+		result := run(func(val Object) bool {
+			if stopped {
+				return false
+			}
+			values <- val
+			stopped = !<-resume
+			return !stopped
+		})
+		if err, ok := result.(*Error); ok && !stopped {
+			values <- err
+		}
+	}()
+}
+
+// Next resumes the function up to its next yield and returns the value,
+// ok is false once the function has returned
+func (g *Generator) Next() (Object, bool) {
+	switch g.state {
+	case generatorDone:
+		return nil, false
+	case generatorNew:
+		g.start()
+	default:
+		g.resume <- true
+	}
+
+	val, ok := <-g.values
+	if !ok {
+		g.done()
+		return nil, false
+	}
+	if _, isErr := val.(*Error); isErr {
+		g.done()
+	} else {
+		g.state = generatorSuspended
+	}
+	return val, true
+}
+
+// Close stops the function at the yield it waits on and waits for it to
+// return, a generator that is done or not started is left as it is
+func (g *Generator) Close() {
+	if g.state == generatorSuspended {
+		g.resume <- false
+		for range g.values {
+		}
+	}
+	g.done()
+}
+
+// done marks the generator as done and drops it from its session
+func (g *Generator) done() {
+	g.state = generatorDone
+	if g.session != nil {
+		delete(g.session.generators, g)
+	}
+}
@@ -0,0 +1,152 @@
+package value
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// counter returns a generator of the integers from 0 to n-1, stopped
+// records whether the function was stopped by Close
+func counter(n int, stopped *bool) *Generator {
+	return NewGenerator("counter", func(yield func(Object) bool) Object {
+		for i := 0; i < n; i++ {
+			if !yield(&Integer{Value: int64(i)}) {
+				*stopped = true
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func TestGeneratorNext(t *testing.T) {
+	stopped := false
+	g := counter(3, &stopped)
+	for i := 0; i < 3; i++ {
+		val, ok := g.Next()
+		if !ok || val.(*Integer).Value != int64(i) {
+			t.Fatalf("Next() wrong. want=%d, got=%v (%t)", i, val, ok)
+		}
+	}
+	if _, ok := g.Next(); ok {
+		t.Errorf("Next() returned a value past the end")
+	}
+	if _, ok := g.Next(); ok {
+		t.Errorf("Next() returned a value after the end")
+	}
+	g.Close()
+	if stopped {
+		t.Errorf("a generator run to its end was stopped")
+	}
+}
+
+func TestGeneratorClose(t *testing.T) {
+	stopped := false
+	g := counter(10, &stopped)
+	g.Next()
+	g.Close()
+	if !stopped {
+		t.Errorf("Close did not stop the function")
+	}
+	if _, ok := g.Next(); ok {
+		t.Errorf("Next() returned a value after Close")
+	}
+
+	// a generator closed before it starts never runs
+	started := false
+	g = NewGenerator("unstarted", func(func(Object) bool) Object {
+		started = true
+		return nil
+	})
+	g.Close()
+	g.Next()
+	if started {
+		t.Errorf("a generator closed before it started ran")
+	}
+}
+
+func TestGeneratorError(t *testing.T) {
+	g := NewGenerator("failing", func(yield func(Object) bool) Object {
+		yield(&Integer{Value: 1})
+		return &Error{Message: "failed"}
+	})
+	g.Next()
+	val, ok := g.Next()
+	if err, isErr := val.(*Error); !ok || !isErr || err.Message != "failed" {
+		t.Errorf("Next() did not return the error. got=%v (%t)", val, ok)
+	}
+	if _, ok := g.Next(); ok {
+		t.Errorf("Next() returned a value after the error")
+	}
+}
+
+func TestSessionClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	session := NewSession()
+	finished, left := false, false
+	done := counter(1, &finished)
+	suspended := counter(10, &left)
+	session.Track(done)
+	session.Track(suspended)
+	for _, ok := done.Next(); ok; _, ok = done.Next() {
+	}
+	suspended.Next()
+	if len(session.generators) != 1 {
+		t.Fatalf("session tracks %d generators, want the suspended one", len(session.generators))
+	}
+
+	session.Close()
+	if !left {
+		t.Errorf("Close did not stop the suspended generator")
+	}
+	if len(session.generators) != 0 {
+		t.Errorf("session still tracks %d generators after Close", len(session.generators))
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("closed session leaked generator goroutines. before=%d, after=%d", before, after)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	hash := &Hash{}
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 2})
+
+	tests := []struct {
+		iterable Object
+		expected []string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}}}, []string{"1", "x"}},
+		{&String{Value: "hé"}, []string{"h", "é"}},
+		{hash, []string{"b", "a"}},
+		{&Array{}, []string{}},
+	}
+
+	for i, tt := range tests {
+		it, ok := Iterate(tt.iterable)
+		if !ok {
+			t.Fatalf("tests[%d] - %s is not iterable", i, tt.iterable.Type())
+		}
+		got := []string{}
+		for val, ok := it.Next(); ok; val, ok = it.Next() {
+			got = append(got, val.Inspect())
+		}
+		if len(got) != len(tt.expected) {
+			t.Fatalf("tests[%d] - wrong elements. want=%v, got=%v", i, tt.expected, got)
+		}
+		for j := range got {
+			if got[j] != tt.expected[j] {
+				t.Errorf("tests[%d] - wrong elements. want=%v, got=%v", i, tt.expected, got)
+			}
+		}
+	}
+
+	if _, ok := Iterate(&Integer{Value: 1}); ok {
+		t.Errorf("integer is iterable")
+	}
+}
@@ -0,0 +1,50 @@
+package value
+
+// Iterator walks the elements of an iterable value
+type Iterator interface {
+	// Next returns the next element, ok is false past the last one
+	Next() (val Object, ok bool)
+	// Close releases the iterator when it is left before its end
+	Close()
+}
+
+// sliceIterator walks a fixed list of values
+type sliceIterator struct {
+	values []Object
+}
+
+func (it *sliceIterator) Next() (Object, bool) {
+	if len(it.values) == 0 {
+		return nil, false
+	}
+	val := it.values[0]
+	it.values = it.values[1:]
+	return val, true
+}
+
+func (it *sliceIterator) Close() {}
+
+// Iterate returns an iterator over the elements of an array, the characters
+// of a string, the keys of a hash or the values of a generator, ok is false
+// for the values that are not iterable
+func Iterate(obj Object) (it Iterator, ok bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return &sliceIterator{values: obj.Elements}, true
+	case *String:
+		chars := []Object{}
+		for _, ch := range obj.Value {
+			chars = append(chars, &String{Value: string(ch)})
+		}
+		return &sliceIterator{values: chars}, true
+	case *Hash:
+		keys := make([]Object, 0, obj.Len())
+		for _, pair := range obj.Pairs() {
+			keys = append(keys, pair.Key)
+		}
+		return &sliceIterator{values: keys}, true
+	case *Generator:
+		return obj, true
+	}
+	return nil, false
+}
@@ -0,0 +1,36 @@
+package value
+
+// Session is the state shared by the environments of one evaluation, a
+// script run or a REPL session: the modules imported so far and the
+// generators that are waiting on a yield
+type Session struct {
+	// Modules caches every imported module by its absolute path,
+	// so a file is only evaluated once no matter how many times it is imported
+	Modules map[string]*Module
+	// Importing is the chain of files being imported, used to detect cycles
+	Importing []string
+
+	generators map[*Generator]bool
+}
+
+func NewSession() *Session {
+	return &Session{Modules: map[string]*Module{}, generators: map[*Generator]bool{}}
+}
+
+// Track makes the session stop g when it is closed, a generator leaves the
+// session on its own once it is done
+func (s *Session) Track(g *Generator) {
+	g.session = s
+}
+
+// Close stops the generators that are still waiting on a yield, so their
+// goroutines end with the evaluation that started them
+func (s *Session) Close() {
+	running := make([]*Generator, 0, len(s.generators))
+	for g := range s.generators {
+		running = append(running, g)
+	}
+	for _, g := range running {
+		g.Close()
+	}
+}
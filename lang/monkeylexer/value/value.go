@@ -2,11 +2,15 @@ package value
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math/big"
 	"strings"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/delavalom/arvlang/lang/monkeylexer/ast"
+	"github.com/delavalom/arvlang/lang/monkeylexer/token"
 )
 
 type ObjectType string
@@ -27,6 +31,9 @@ const (
 	BUILTIN_VAL      = "BUILTIN"
 	ARRAY_VAL        = "ARRAY"
 	HASH_VAL         = "HASH"
+	MODULE_VAL       = "MODULE"
+	BIG_INTEGER_VAL  = "BIG_INTEGER"
+	GENERATOR_VAL    = "GENERATOR"
 )
 
 type Integer struct {
@@ -36,6 +43,24 @@ type Integer struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_VAL }
 
+// BigInteger is an integer out of the int64 range, integer operations
+// promote to it when they overflow
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Inspect() string  { return bi.Value.String() }
+func (bi *BigInteger) Type() ObjectType { return BIG_INTEGER_VAL }
+
+// NewInteger returns an Integer when n fits in an int64 and a BigInteger
+// otherwise, so every integer value has a single representation
+func NewInteger(n *big.Int) Object {
+	if n.IsInt64() {
+		return &Integer{Value: n.Int64()}
+	}
+	return &BigInteger{Value: n}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -58,27 +83,36 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 type Error struct {
 	Message    string
 	StackTrace string
+	// Span is the expression that raised the error, zero when unknown
+	Span diagnostic.Span
 }
 
 func (e *Error) Type() ObjectType { return ERROR_VAL }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// Diagnostic returns the error as a runtime diagnostic
+func (e *Error) Diagnostic() diagnostic.Diagnostic {
+	return diagnostic.Errorf(e.Span, diagnostic.Runtime, "%s", e.Message)
+}
+
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // see ast.FunctionLiteral
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string
+	Token      token.Token // the fn token, where the function is defined
+	// Generator is set when the body yields, a call returns a Generator
+	Generator bool
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_VAL }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParametersString(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -121,16 +155,78 @@ type Hashable interface {
 	HashKey() HashKey
 }
 
+// Hash maps keys to values, the zero value is an empty hash. The pairs are
+// kept in insertion order and bucketed by the digest of their key, the real
+// keys are compared inside a bucket so colliding keys stay apart
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	// Hasher returns the digest of a key and whether it is usable as a key,
+	// HashKeyOf when nil
+	Hasher func(key Object) (HashKey, bool)
+
+	pairs   []HashPair
+	buckets map[HashKey][]int
+}
+
+func (h *Hash) hashKey(key Object) (HashKey, bool) {
+	if h.Hasher != nil {
+		return h.Hasher(key)
+	}
+	return HashKeyOf(key)
+}
+
+// lookup returns the digest of key and the index of its pair,
+// -1 when there is none
+func (h *Hash) lookup(key Object) (HashKey, int, bool) {
+	digest, ok := h.hashKey(key)
+	if !ok {
+		return digest, -1, false
+	}
+	for _, i := range h.buckets[digest] {
+		if Equal(h.pairs[i].Key, key) {
+			return digest, i, true
+		}
+	}
+	return digest, -1, true
+}
+
+// Get returns the value of key, ok is false when key is not in the hash
+func (h *Hash) Get(key Object) (val Object, ok bool) {
+	if _, i, _ := h.lookup(key); i >= 0 {
+		return h.pairs[i].Value, true
+	}
+	return nil, false
 }
 
+// Set binds key to val, it returns false when key is not usable as a key
+func (h *Hash) Set(key, val Object) bool {
+	digest, i, ok := h.lookup(key)
+	if !ok {
+		return false
+	}
+	if i >= 0 {
+		h.pairs[i].Value = val
+		return true
+	}
+	if h.buckets == nil {
+		h.buckets = map[HashKey][]int{}
+	}
+	h.buckets[digest] = append(h.buckets[digest], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: val})
+	return true
+}
+
+// Len returns the number of pairs in the hash
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Pairs returns the pairs of the hash in insertion order
+func (h *Hash) Pairs() []HashPair { return h.pairs }
+
 func (h *Hash) Type() ObjectType { return HASH_VAL }
 
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -162,8 +258,48 @@ func (b *Boolean) HashKey() HashKey {
 func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
+func (bi *BigInteger) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(bi.Value.Bytes())
+	if bi.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	return HashKey{Type: bi.Type(), Value: h.Sum64()}
+}
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
+
+// HashKeyOf returns the hash key of obj, ok is false when obj is not usable
+// as a key. An array is hashed from the keys of its elements, so it can be a
+// key when all of them can, as [x, y]
+func HashKeyOf(obj Object) (key HashKey, ok bool) {
+	switch obj := obj.(type) {
+	case Hashable:
+		return obj.HashKey(), true
+	case *Array:
+		h := fnv.New64a()
+		for _, e := range obj.Elements {
+			key, ok := HashKeyOf(e)
+			if !ok {
+				return HashKey{}, false
+			}
+			h.Write([]byte(key.Type))
+			h.Write(binary.LittleEndian.AppendUint64(nil, key.Value))
+		}
+		return HashKey{Type: obj.Type(), Value: h.Sum64()}, true
+	}
+	return HashKey{}, false
+}
+
+// Module is the value of an import expression, its attributes are the
+// top-level let bindings of the imported file
+type Module struct {
+	Name  string
+	Attrs *Hash
+}
+
+func (m *Module) Type() ObjectType { return MODULE_VAL }
+func (m *Module) Inspect() string  { return "<module " + m.Name + ">" }
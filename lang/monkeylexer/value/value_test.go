@@ -1,6 +1,9 @@
 package value
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +20,119 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestBigIntegerHashKey(t *testing.T) {
+	big1 := NewInteger(new(big.Int).Lsh(big.NewInt(1), 70)).(*BigInteger)
+	big2 := NewInteger(new(big.Int).Lsh(big.NewInt(1), 70)).(*BigInteger)
+	neg := NewInteger(new(big.Int).Neg(big1.Value)).(*BigInteger)
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+	if big1.HashKey() == neg.HashKey() {
+		t.Errorf("big integers with different sign have same hash keys")
+	}
+	if _, ok := NewInteger(big.NewInt(42)).(*Integer); !ok {
+		t.Errorf("NewInteger does not return an Integer for values in the int64 range")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 70)
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &BigInteger{Value: big.NewInt(1)}, true},
+		{&BigInteger{Value: huge}, NewInteger(new(big.Int).Set(huge)), true},
+		{&BigInteger{Value: huge}, &Integer{Value: 1}, false},
+		{&Nil{}, &Nil{}, true},
+		{&Nil{}, &Boolean{Value: false}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{
+			&Array{Elements: []Object{&Integer{Value: 1}, &Array{Elements: []Object{&String{Value: "x"}}}}},
+			&Array{Elements: []Object{&Integer{Value: 1}, &Array{Elements: []Object{&String{Value: "x"}}}}},
+			true,
+		},
+		{&Array{}, &Array{Elements: []Object{&Nil{}}}, false},
+		{&Array{}, &Hash{}, false},
+	}
+
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. expected=%t, got=%t", i, tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+		if got := Equal(tt.b, tt.a); got != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. expected=%t, got=%t", i, tt.b.Inspect(), tt.a.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	pair := func(a, b Object) Object { return &Array{Elements: []Object{a, b}} }
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+
+	key1, ok1 := HashKeyOf(pair(one, two))
+	key2, ok2 := HashKeyOf(pair(&Integer{Value: 1}, &Integer{Value: 2}))
+	if !ok1 || !ok2 || key1 != key2 {
+		t.Errorf("arrays with same elements have different hash keys")
+	}
+	if key, _ := HashKeyOf(pair(two, one)); key == key1 {
+		t.Errorf("arrays with elements in a different order have same hash keys")
+	}
+	if key, _ := HashKeyOf(pair(&String{Value: "1"}, two)); key == key1 {
+		t.Errorf("arrays with elements of different types have same hash keys")
+	}
+	if _, ok := HashKeyOf(pair(one, &Function{})); ok {
+		t.Errorf("array with a function is usable as hash key")
+	}
+	if _, ok := HashKeyOf(&Hash{}); ok {
+		t.Errorf("hash is usable as hash key")
+	}
+}
+
+func TestHashCollisions(t *testing.T) {
+	// every key collides under this hasher
+	hash := &Hash{Hasher: func(Object) (HashKey, bool) { return HashKey{Type: STRING_VAL}, true }}
+	a, b := &String{Value: "a"}, &String{Value: "b"}
+
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 3})
+
+	if hash.Len() != 2 {
+		t.Fatalf("hash has wrong length. want=2, got=%d", hash.Len())
+	}
+	tests := []struct {
+		key      Object
+		expected int64
+	}{
+		{a, 3},
+		{b, 2},
+	}
+	for i, tt := range tests {
+		val, ok := hash.Get(tt.key)
+		if !ok {
+			t.Fatalf("tests[%d] - no value for %s", i, tt.key.Inspect())
+		}
+		if val.(*Integer).Value != tt.expected {
+			t.Errorf("tests[%d] - wrong value for %s. want=%d, got=%d", i, tt.key.Inspect(), tt.expected, val.(*Integer).Value)
+		}
+		if key := hash.Pairs()[i].Key; key != tt.key {
+			t.Errorf("tests[%d] - pairs out of insertion order. got=%s", i, key.Inspect())
+		}
+	}
+	if _, ok := hash.Get(&String{Value: "c"}); ok {
+		t.Errorf("hash has a value for a missing key")
+	}
+}
+
+func TestHashUnusableKey(t *testing.T) {
+	hash := &Hash{}
+	if hash.Set(&Function{}, &Nil{}) {
+		t.Errorf("hash accepted a function as key")
+	}
+	if _, ok := hash.Get(&Function{}); ok || hash.Len() != 0 {
+		t.Errorf("hash has a value for a function key")
+	}
+}
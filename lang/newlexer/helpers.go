@@ -1,9 +1,10 @@
 package newlexer
 
 import (
-	"fmt"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
 )
 
 // next returns the next rune in the input.
@@ -123,7 +124,19 @@ func isCompositeAssignment(a rune, b rune) bool {
 
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.run.
+// The error points at the start of the item being scanned.
 func (l *Lexer) errorf(format string, args ...interface{}) StateFn {
-	l.items <- Item{itemError, fmt.Sprintf(format, args...)}
+	span := l.position(l.start)
+	d := diagnostic.Errorf(span, diagnostic.Lexical, format, args...)
+	l.items <- Item{Type: itemError, Value: d.Message, Diagnostic: &d}
 	return nil
 }
+
+// position returns the span of the rune at offset in the input.
+func (l *Lexer) position(offset int) diagnostic.Span {
+	line := 1 + strings.Count(l.input[:offset], "\n")
+	column := 1 + utf8.RuneCountInString(l.input[strings.LastIndex(l.input[:offset], "\n")+1:offset])
+	span := diagnostic.Point(line, column)
+	span.File = l.name
+	return span
+}
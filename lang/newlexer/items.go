@@ -1,6 +1,10 @@
 package newlexer
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/delavalom/arvlang/lang/diagnostic"
+)
 
 type itemType int
 
@@ -32,6 +36,8 @@ const (
 type Item struct {
 	Type  itemType
 	Value string
+	// Diagnostic locates the error of an itemError item
+	Diagnostic *diagnostic.Diagnostic
 }
 
 func (i Item) String() string {
@@ -3,6 +3,7 @@ package newlexer
 import (
 	"testing"
 
+	"github.com/delavalom/arvlang/lang/diagnostic"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -235,4 +236,22 @@ func TestInvalidCharacter(t *testing.T) {
 
 	assert.Equal(t, actualItem.Type, item.Type)
 	assert.Equal(t, actualItem.Value, item.Value)
+	assert.Equal(t, "Invalid Character:1:1", actualItem.Diagnostic.Span.String())
+}
+
+func TestErrorPosition(t *testing.T) {
+	input := "x = 1\n  y ☂"
+	result := lex("Error Position", input)
+
+	// the items are read to the end so the lexer goroutine finishes
+	var errorItem Item
+	found := false
+	for item := range result.items {
+		if item.Type == itemError && !found {
+			errorItem, found = item, true
+		}
+	}
+
+	assert.True(t, found)
+	assert.Equal(t, diagnostic.Position{Line: 2, Column: 5}, errorItem.Diagnostic.Span.Start)
 }
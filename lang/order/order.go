@@ -6,11 +6,12 @@ const (
 	Comma          = -10
 	Postfix        = 0
 	Assign         = 20
-	Conditional    = 30
+	Conditional    = 30 // c ? a : b, right associative
 	Or             = 40
 	And            = 50
 	Not            = 60
 	Comparison     = 70
+	Pipe           = 75 // x |> f(y), binds looser than arithmetic
 	Addition       = 80
 	Subtraction    = 80
 	Multiplication = 90
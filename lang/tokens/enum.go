@@ -18,7 +18,7 @@ const (
 	String     = "string"     // '.*'
 
 	// Operators
-	Operator   = "operator"   // +, -, *, /, %, **, <, <=, >, >=, ==, !=
+	Operator   = "operator"   // +, -, *, /, %, **, <, <=, >, >=, ==, !=, |>, ?
 	Assignment = "assignment" // =, +=, -=, *=, /=,
 
 	// Separators